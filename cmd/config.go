@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the project config.",
+	Long:  `Work with the project config file.`,
+}
+
+var configConvertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert the config file to another format.",
+	Long: `Convert the config file to YAML, JSON or TOML. The converted file replaces
+the original one. Files included by the config are left as they are.`,
+	Run: convertConfig,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [path]",
+	Short: "Print a value from the config.",
+	Long: `Print the value at a dotted path into the config, e.g. name, vars.region or
+services.api.port. Services and resources are addressed by name. Single values
+are printed as is, everything else is printed as JSON.`,
+	Args: cobra.ExactArgs(1),
+	Run:  getConfigValue,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set [path] [value]",
+	Short: "Set a value in the config.",
+	Long: `Set the value at a dotted path into the config, e.g. services.api.port 8080.
+The value is converted to the type of the field, lists are comma separated. The
+changed config is validated before it is written and the rest of the file is
+left as it is.`,
+	Args: cobra.ExactArgs(2),
+	Run:  setConfigValue,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset [path]",
+	Short: "Remove a value from the config.",
+	Long: `Remove the value at a dotted path from the config, e.g. vars.region. Unsetting
+services.<name> or resources.<name> removes the service or resource.`,
+	Args: cobra.ExactArgs(1),
+	Run:  unsetConfigValue,
+}
+
+var configConvertTo string
+
+func getConfigValue(cmd *cobra.Command, args []string) {
+	appConfig, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	value, err := appConfig.GetValue(args[0])
+	utils.IfErrorExit(err, "couldn't read config value")
+
+	switch value.(type) {
+	case string, int, bool:
+		fmt.Println(value)
+	default:
+		contents, err := json.MarshalIndent(value, "", "    ")
+		utils.IfErrorExit(err, "couldn't print config value")
+		fmt.Println(string(contents))
+	}
+}
+
+func setConfigValue(cmd *cobra.Command, args []string) {
+	appConfig, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	err = appConfig.CheckWritable()
+	utils.IfErrorExit(err, "couldn't change config")
+
+	err = appConfig.SetValue(args[0], args[1])
+	utils.IfErrorExit(err, "couldn't set config value")
+
+	err = appConfig.SaveConfigFile()
+	utils.IfErrorExit(err, "couldn't save config")
+
+	fmt.Printf("✅ Set %s.\n", args[0])
+}
+
+func unsetConfigValue(cmd *cobra.Command, args []string) {
+	appConfig, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	err = appConfig.CheckWritable()
+	utils.IfErrorExit(err, "couldn't change config")
+
+	err = appConfig.UnsetValue(args[0])
+	utils.IfErrorExit(err, "couldn't unset config value")
+
+	err = appConfig.SaveConfigFile()
+	utils.IfErrorExit(err, "couldn't save config")
+
+	fmt.Printf("✅ Unset %s.\n", args[0])
+}
+
+func convertConfig(cmd *cobra.Command, args []string) {
+	filePath, configType, err := jacuik_config.LocateConfigFile()
+	utils.IfErrorExit(err, "couldn't find config file")
+
+	if configType == configConvertTo {
+		utils.ThrowError(fmt.Sprintf("%s is already a %s file.\n", filepath.Base(filePath), configConvertTo))
+	}
+
+	convertedPath, err := convertConfigFile(filePath, configType, configConvertTo)
+	utils.IfErrorExit(err, "couldn't convert config file")
+
+	if configConvertTo == "json" {
+		fmt.Println("⚠️  JSON doesn't support comments, any comments in the config were dropped.")
+	}
+
+	fmt.Printf("✅ Converted %s to %s.\n", filepath.Base(filePath), filepath.Base(convertedPath))
+}
+
+// convertConfigFile writes the config file in another format, checks the
+// result and removes the original file.
+func convertConfigFile(filePath, configType, typ string) (string, error) {
+	file, err := jacuik_config.LoadConfigFile(filePath, configType)
+	if err != nil {
+		return "", err
+	}
+
+	converted, err := file.Convert(typ)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(converted.Path); err == nil {
+		return "", fmt.Errorf("The file [%s] already exists.", converted.Path)
+	}
+
+	contents, err := converted.Marshal()
+	if err != nil {
+		return "", err
+	}
+
+	validationErrors := jacuik_config.ValidateConfig(converted.Path, contents)
+	if len(validationErrors) > 0 {
+		return "", validationErrors
+	}
+
+	err = converted.Save()
+	if err != nil {
+		return "", err
+	}
+
+	// The converted file points editors at the JSON Schema, make sure it
+	// is there like it is when the config is written out.
+	err = jacuik_config.WriteJSONSchemaFile(filepath.Join(filepath.Dir(converted.Path), jacuik_config.JSONSchemaFilePath))
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Remove(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	return converted.Path, nil
+}
+
+func init() {
+	configConvertCmd.Flags().StringVar(&configConvertTo, "to", "", "the format to convert to: yaml, json or toml")
+	configConvertCmd.MarkFlagRequired("to")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configConvertCmd)
+	RootCmd.AddCommand(configCmd)
+}
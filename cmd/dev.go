@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/infrastructure"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/local"
+	"github.com/zchase/jacuik/pkg/terminal"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Run the project locally with Docker.",
+	Long: `Run every service in the project locally with Docker.
+
+The image of each service is built from its Dockerfile and the services are
+started on a shared network where they reach each other by name, like
+http://api:8080. Public web services are exposed on local ports starting at
+--port, behind a proxy that forwards requests like the load balancer does
+once deployed. Services start after the services they depend on.
+
+Resources run in the same containers as in jacuik export compose, like
+postgres:14-alpine for postgres, and their data is kept in a volume from
+one run to the next. Services get the URL of everything they depend on in
+variables like DB_URL. The logs of every service are shown as they come in,
+with the state of each service below them.
+
+When a file in the build context of a service changes the service is
+rebuilt and restarted, the others keep running. Files matched by the
+.dockerignore of the build context are left out.
+
+Press Ctrl+C to stop the services and remove their containers.`,
+	Args: cobra.NoArgs,
+	Run:  dev,
+}
+
+var devProxyPort int
+
+func dev(cmd *cobra.Command, args []string) {
+	jacuik_config.RegisterInterpolationProvider("stack", infrastructure.StackOutputProvider(infrastructureProjectName))
+
+	config, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	var services []string
+	for _, svc := range config.Services {
+		services = append(services, svc.Name)
+	}
+
+	// ctrl+c is read by the view, the signals stop jacuik dev when it is
+	// run without a terminal or stopped by another process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	view := terminal.NewDevView(services)
+	env := local.NewEnvironment(config, devProxyPort, view.Logs())
+	env.OnStatus = view.SetStatus
+
+	runErr := view.Start(ctx, func(ctx context.Context) error {
+		err := env.Start(ctx)
+		if err != nil {
+			return err
+		}
+
+		return env.Watch(ctx)
+	})
+
+	fmt.Println("Stopping services...")
+	err = env.Stop()
+	utils.IfErrorExit(runErr, "couldn't run the project")
+	utils.IfErrorExit(err, "couldn't stop the project")
+}
+
+func init() {
+	devCmd.Flags().IntVar(&devProxyPort, "port", 8080, "the local port of the first public service, the others get the ports after it")
+
+	RootCmd.AddCommand(devCmd)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/convert"
+	"github.com/zchase/jacuik/pkg/infrastructure"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the project for other tools.",
+	Long:  `Export the project so it can be run with other tools.`,
+}
+
+var exportComposeCmd = &cobra.Command{
+	Use:   "compose",
+	Short: "Export the project as a docker-compose.yaml.",
+	Long: `Export the project as a docker-compose.yaml.
+
+Every service is built from its Dockerfile. Public web services are
+published on the host starting at --port, the others are only reachable
+from the other services. Resources are replaced by local stand-ins:
+Postgres, Redis and ElasticMQ for queues.
+
+Each service gets PORT, the port it listens on, <NAME>_URL for every
+service and resource in its dependsOn, like DB_URL for a resource named db,
+and the variables in its env. Values from ${env:NAME} aren't written out,
+they are left as ${NAME} for compose to fill in from the environment or a
+.env file when the project is started.`,
+	Args: cobra.NoArgs,
+	Run:  exportCompose,
+}
+
+var exportKubernetesCmd = &cobra.Command{
+	Use:   "k8s",
+	Short: "Export the project as Kubernetes manifests or a Helm chart.",
+	Long: `Export the project as Kubernetes manifests, a file for each service.
+
+Every service gets a Deployment with probes on its port, a Service, a
+ConfigMap and Secret holding its environment and a HorizontalPodAutoscaler.
+The env of a service is kept in its Secret. Values from ${env:NAME} aren't
+written out, they are left as ${NAME} for envsubst to fill in when the
+manifests are applied.
+Public web services also get an Ingress, routed from <service>.<domain>
+when --domain is set. Without it the only public service is routed from
+any host, --domain is needed when there are more. Resources are replaced by stand-ins like in
+` + "`jacuik export compose`" + `.
+
+Images are named <registry>/<project>-<service>:<tag>, push them there
+before applying the manifests.
+
+With --helm a Helm chart is written instead, with a values file for each of
+--environments to use on top of values.yaml. The env of the services isn't
+in the chart, it is set with --set-string services.<service>.envValues.<NAME>
+when the chart is installed.`,
+	Args: cobra.NoArgs,
+	Run:  exportKubernetes,
+}
+
+var exportOutputPath string
+var exportComposePort int
+var exportKubernetesHelm bool
+var exportKubernetesOptions convert.KubernetesOptions
+
+func exportCompose(cmd *cobra.Command, args []string) {
+	jacuik_config.RegisterInterpolationProvider("stack", infrastructure.StackOutputProvider(infrastructureProjectName))
+	// Values from the environment are often secrets, compose fills them in
+	// when the project is started instead of them being written into it.
+	jacuik_config.LeaveUnresolved("env")
+
+	config, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	outputPath := exportOutputPath
+	if outputPath == "" {
+		projectDirectory, err := config.ProjectDirectory()
+		utils.IfErrorExit(err, "couldn't find the project directory")
+
+		outputPath = filepath.Join(projectDirectory, "docker-compose.yaml")
+	}
+
+	outputDirectory, err := filepath.Abs(filepath.Dir(outputPath))
+	utils.IfErrorExit(err, "couldn't find the output directory")
+
+	compose, err := convert.ToCompose(config, outputDirectory, exportComposePort)
+	utils.IfErrorExit(err, "couldn't convert the project")
+
+	contents, err := compose.Marshal(config)
+	utils.IfErrorExit(err, "couldn't write docker-compose.yaml")
+
+	contents = append([]byte("# Generated by `jacuik export compose`, changes are lost when it is run again.\n"), contents...)
+	err = os.WriteFile(outputPath, contents, 0644)
+	utils.IfErrorExit(err, "couldn't write docker-compose.yaml")
+
+	fmt.Printf("✅ Project exported to %s. Run `docker compose -f %s up` to start it.\n", outputPath, outputPath)
+	if _, hasEnvExpressions := serviceEnvUsage(config); hasEnvExpressions {
+		fmt.Printf("The values from ${env:NAME} are read from the environment or a .env file in %s when it starts.\n", filepath.Dir(outputPath))
+	}
+}
+
+func exportKubernetes(cmd *cobra.Command, args []string) {
+	jacuik_config.RegisterInterpolationProvider("stack", infrastructure.StackOutputProvider(infrastructureProjectName))
+	// Values from the environment are often secrets, they are filled in
+	// when the export is deployed instead of being written into it.
+	jacuik_config.LeaveUnresolved("env")
+
+	config, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	outputDirectory := exportOutputPath
+	if outputDirectory == "" {
+		projectDirectory, err := config.ProjectDirectory()
+		utils.IfErrorExit(err, "couldn't find the project directory")
+
+		outputDirectory = filepath.Join(projectDirectory, "k8s")
+		if exportKubernetesHelm {
+			outputDirectory = filepath.Join(projectDirectory, "chart")
+		}
+	}
+
+	files := make(map[string][]byte)
+	if exportKubernetesHelm {
+		files, err = convert.ToHelmChart(config, exportKubernetesOptions)
+		utils.IfErrorExit(err, "couldn't convert the project")
+	} else {
+		manifests, err := convert.ToKubernetes(config, exportKubernetesOptions)
+		utils.IfErrorExit(err, "couldn't convert the project")
+
+		for _, manifest := range manifests {
+			contents, err := manifest.Marshal()
+			utils.IfErrorExit(err, "couldn't write the manifests")
+
+			files[manifest.Name] = append([]byte("# Generated by `jacuik export k8s`, changes are lost when it is run again.\n"), contents...)
+		}
+	}
+
+	for name, contents := range files {
+		filePath := filepath.Join(outputDirectory, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(filePath), 0755)
+		utils.IfErrorExit(err, "couldn't create the output directory")
+
+		err = os.WriteFile(filePath, contents, 0644)
+		utils.IfErrorExit(err, fmt.Sprintf("couldn't write %s", name))
+	}
+
+	hasEnv, hasEnvExpressions := serviceEnvUsage(config)
+	if exportKubernetesHelm {
+		fmt.Printf("✅ Helm chart written to %s. Run `helm upgrade --install %s %s` to deploy it.\n", outputDirectory, config.Name, outputDirectory)
+		if hasEnv {
+			fmt.Println("The env of the services is set with --set-string services.<service>.envValues.<NAME>=value.")
+		}
+		return
+	}
+
+	if hasEnvExpressions {
+		fmt.Printf("✅ Manifests written to %s. Run `for f in %s/*.yaml; do envsubst < $f | kubectl apply -f -; done` to fill in the values from the environment and deploy them.\n", outputDirectory, outputDirectory)
+		return
+	}
+
+	fmt.Printf("✅ Manifests written to %s. Run `kubectl apply -f %s` to deploy them.\n", outputDirectory, outputDirectory)
+}
+
+// serviceEnvUsage reports whether any service has an env, and whether any
+// of it is left as ${env:NAME} to be filled in when the export is used.
+func serviceEnvUsage(config *jacuik_config.AppConfig) (hasEnv bool, hasEnvExpressions bool) {
+	for _, svc := range config.Services {
+		for _, value := range svc.Env {
+			hasEnv = true
+			hasEnvExpressions = hasEnvExpressions || strings.Contains(value, "${env:")
+		}
+	}
+
+	return hasEnv, hasEnvExpressions
+}
+
+func init() {
+	exportCmd.PersistentFlags().StringVarP(&exportOutputPath, "output", "o", "", "where to write the export, by default in the project directory")
+	exportComposeCmd.Flags().IntVar(&exportComposePort, "port", 8080, "the host port of the first public service, the others get the ports after it")
+
+	exportKubernetesCmd.Flags().BoolVar(&exportKubernetesHelm, "helm", false, "write a Helm chart instead of manifests")
+	exportKubernetesCmd.Flags().StringVar(&exportKubernetesOptions.Registry, "registry", "", "the registry the images are pushed to")
+	exportKubernetesCmd.Flags().StringVar(&exportKubernetesOptions.Tag, "tag", "latest", "the tag of the images")
+	exportKubernetesCmd.Flags().StringVar(&exportKubernetesOptions.Domain, "domain", "", "route <service>.<domain> to each public service, needed when there is more than one")
+	exportKubernetesCmd.Flags().StringSliceVar(&exportKubernetesOptions.Environments, "environments", []string{"dev", "prod"}, "the environments of the Helm chart, each gets a values file")
+
+	exportCmd.AddCommand(exportComposeCmd)
+	exportCmd.AddCommand(exportKubernetesCmd)
+	RootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/convert"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Create a project from another tool's config.",
+	Long:  `Create a project from the config of another tool.`,
+}
+
+var importComposeCmd = &cobra.Command{
+	Use:   "compose [file]",
+	Short: "Create a project from a docker-compose.yaml.",
+	Long: `Create a schema.yaml in the current directory from a docker-compose.yaml,
+by default the one in the current directory.
+
+Services with a build become services, using the port of their first ports
+or expose entry. Services with ports are public and services without
+a port become workers. Postgres, Redis, RabbitMQ and ElasticMQ services
+become resources. Their environment, depends_on and build context are
+carried over, compose variables like ${NAME} become ${env:NAME}.
+
+Everything that can't be carried over, like volumes or prebuilt images, is
+left out with a warning.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  importCompose,
+}
+
+// composeFileNames are the names docker compose looks for, in order.
+var composeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+func importCompose(cmd *cobra.Command, args []string) {
+	workingDirectory, err := os.Getwd()
+	utils.IfErrorExit(err, "couldn't read the working directory")
+
+	existingConfig, _, err := jacuik_config.FindConfigFile(workingDirectory)
+	if err == nil && filepath.Dir(existingConfig) == workingDirectory {
+		utils.ThrowError(fmt.Sprintf("The current directory already has a config [%s].\n", filepath.Base(existingConfig)))
+	}
+
+	composePath := ""
+	if len(args) > 0 {
+		composePath = args[0]
+	} else {
+		for _, name := range composeFileNames {
+			if _, err := os.Stat(name); err == nil {
+				composePath = name
+				break
+			}
+		}
+
+		if composePath == "" {
+			utils.ThrowError("No docker-compose.yaml was found in the current directory, pass the path to one.\n")
+		}
+	}
+
+	appConfig, warnings, err := convert.FromCompose(composePath, workingDirectory)
+	utils.IfErrorExit(err, "couldn't import the compose file")
+
+	err = appConfig.WriteOutConfigFile("yaml")
+	utils.IfErrorExit(err, "couldn't write out config file")
+
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+
+	if _, _, err := jacuik_config.ParseJacuikConfig(); err != nil {
+		fmt.Printf("⚠️  The imported config isn't valid yet, run `jacuik validate` after fixing it:\n\n%s\n\n", err)
+	}
+
+	fmt.Printf("✅ Project imported from %s.\n", composePath)
+}
+
+func init() {
+	importCmd.AddCommand(importComposeCmd)
+	RootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/lsp"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Start the language server.",
+	Long:  `Start a language server for schema.yaml and schema.json that communicates over stdio.`,
+	Run:   startLanguageServer,
+}
+
+func startLanguageServer(cmd *cobra.Command, args []string) {
+	server := lsp.NewServer(os.Stdin, os.Stdout)
+	err := server.Run()
+	utils.IfErrorExit(err, "language server stopped")
+}
+
+func init() {
+	RootCmd.AddCommand(lspCmd)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/terminal"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the project config.",
+	Long:  `Upgrade the project config file to the current version, showing the changes before they are written.`,
+	Run:   migrate,
+}
+
+var (
+	migrateDryRun bool
+	migrateYes    bool
+)
+
+func migrate(cmd *cobra.Command, args []string) {
+	filePath, configType, err := jacuik_config.LocateConfigFile()
+	utils.IfErrorExit(err, "couldn't find config file")
+
+	original, err := os.ReadFile(filePath)
+	utils.IfErrorExit(err, "couldn't read config file")
+
+	file, err := jacuik_config.LoadConfigFile(filePath, configType)
+	utils.IfErrorExit(err, "couldn't load config file")
+
+	applied, err := file.Migrate()
+	utils.IfErrorExit(err, "couldn't migrate config file")
+
+	if len(applied) == 0 {
+		fmt.Printf("✅ %s is already at version %d.\n", filepath.Base(filePath), jacuik_config.CurrentConfigVersion)
+		return
+	}
+
+	migrated, err := file.Marshal()
+	utils.IfErrorExit(err, "couldn't render migrated config file")
+
+	fmt.Printf("Migrations for %s:\n\n", filepath.Base(filePath))
+	for _, migration := range applied {
+		fmt.Printf("  %d → %d: %s\n", migration.From, migration.From+1, migration.Description)
+	}
+	fmt.Printf("\n%s\n", renderLineDiff(string(original), string(migrated)))
+
+	if migrateDryRun {
+		return
+	}
+
+	if !migrateYes {
+		confirm, err := terminal.NewChoicePrompt("Write these changes?", []string{"yes", "no"})
+		utils.IfErrorExit(err, "couldn't confirm migration")
+		if confirm != "yes" {
+			fmt.Println("Migration cancelled.")
+			return
+		}
+	}
+
+	// Make sure the upgraded file is valid before replacing the original.
+	validationErrors := jacuik_config.ValidateConfig(filePath, migrated)
+	if len(validationErrors) > 0 {
+		utils.IfErrorExit(validationErrors, "the migrated config file is invalid")
+	}
+
+	err = file.Save()
+	utils.IfErrorExit(err, "couldn't write config file")
+
+	fmt.Printf("✅ %s migrated to version %d.\n", filepath.Base(filePath), jacuik_config.CurrentConfigVersion)
+}
+
+// renderLineDiff returns a colored line by line diff of two files.
+func renderLineDiff(before, after string) string {
+	dmp := diffmatchpatch.New()
+	beforeChars, afterChars, lines := dmp.DiffLinesToChars(before, after)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(beforeChars, afterChars, false), lines)
+
+	s := strings.Builder{}
+	for _, diff := range diffs {
+		for _, line := range strings.SplitAfter(diff.Text, "\n") {
+			if line == "" {
+				continue
+			}
+			line = strings.TrimSuffix(line, "\n")
+
+			switch diff.Type {
+			case diffmatchpatch.DiffInsert:
+				s.WriteString(utils.TextColor("+ "+line, "#25a78b"))
+			case diffmatchpatch.DiffDelete:
+				s.WriteString(utils.TextColor("- "+line, "#e53e3e"))
+			default:
+				s.WriteString("  " + line)
+			}
+			s.WriteString("\n")
+		}
+	}
+
+	return s.String()
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "show the changes without writing them")
+	migrateCmd.Flags().BoolVarP(&migrateYes, "yes", "y", false, "write the changes without asking for confirmation")
+
+	RootCmd.AddCommand(migrateCmd)
+}
@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/templates"
 	"github.com/zchase/jacuik/pkg/terminal"
 	"github.com/zchase/jacuik/pkg/utils"
 )
@@ -16,7 +20,16 @@ var newProjectCmd = &cobra.Command{
 	Run:   createNewProject,
 }
 
+var (
+	projectStarterName  string
+	projectConfigFormat string
+)
+
 func createNewProject(cmd *cobra.Command, args []string) {
+	if projectConfigFormat != "" && !jacuik_config.IsConfigType(projectConfigFormat) {
+		utils.ThrowError(fmt.Sprintf("Unknown config format [%s], expected one of %s.\n", projectConfigFormat, strings.Join(jacuik_config.ConfigTypes, ", ")))
+	}
+
 	// Check the working directory is empty and if it isn't throw
 	// an error.
 	isWorkingDirectoryEmpty, err := utils.IsCurrentDirectoryEmpty()
@@ -37,14 +50,23 @@ func createNewProject(cmd *cobra.Command, args []string) {
 	projectDescription, err := terminal.NewTextPrompt("How would you describe your project?", defaultProjectDescription)
 	utils.IfErrorExit(err, "couldn't set project description")
 
+	if projectStarterName != "" {
+		createProjectFromStarter(projectName, projectDescription)
+		return
+	}
+
 	appConfig := &jacuik_config.AppConfig{
+		Version:     jacuik_config.CurrentConfigVersion,
 		Name:        projectName,
 		Description: projectDescription,
 	}
 
 	// Schema file type
-	schemaFileType, err := terminal.NewChoicePrompt("How would you like to author your config?", []string{"yaml", "json"})
-	utils.IfErrorExit(err, "couldn't set config language")
+	schemaFileType := projectConfigFormat
+	if schemaFileType == "" {
+		schemaFileType, err = terminal.NewChoicePrompt("How would you like to author your config?", jacuik_config.ConfigTypes)
+		utils.IfErrorExit(err, "couldn't set config language")
+	}
 
 	err = appConfig.WriteOutConfigFile(schemaFileType)
 	utils.IfErrorExit(err, "couldn't write out config file")
@@ -52,6 +74,43 @@ func createNewProject(cmd *cobra.Command, args []string) {
 	fmt.Println("✅ Project created.")
 }
 
+func createProjectFromStarter(projectName, projectDescription string) {
+	starter, err := templates.GetProjectStarter(projectStarterName)
+	utils.IfErrorExit(err, "couldn't load project starter")
+
+	wd, err := os.Getwd()
+	utils.IfErrorExit(err, "couldn't get current working directory")
+
+	err = starter.Render(wd, "", templates.StarterData{
+		ProjectName: projectName,
+		Description: projectDescription,
+	})
+	utils.IfErrorExit(err, "couldn't render project starter")
+
+	err = jacuik_config.WriteJSONSchemaFile(filepath.Join(wd, jacuik_config.JSONSchemaFilePath))
+	utils.IfErrorExit(err, "couldn't write JSON Schema")
+
+	// Starters are written in YAML, convert them when another format was asked for.
+	if projectConfigFormat != "" && projectConfigFormat != "yaml" {
+		_, err = convertConfigFile(filepath.Join(wd, "schema.yaml"), "yaml", projectConfigFormat)
+		utils.IfErrorExit(err, "couldn't convert the starter config")
+	}
+
+	// Make sure the starter produced a usable schema file.
+	_, _, err = jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "project starter did not produce a valid config")
+
+	err = utils.InitGitRepository(wd)
+	if err != nil {
+		fmt.Printf("⚠️  Couldn't initialize a git repository: %s\n", err)
+	}
+
+	fmt.Printf("✅ Project created from the %s starter.\n", starter.Name)
+}
+
 func init() {
+	newProjectCmd.Flags().StringVarP(&projectStarterName, "starter", "s", "", "a built-in starter or a path to a starter directory to create the project from")
+	newProjectCmd.Flags().StringVarP(&projectConfigFormat, "format", "f", "", "the format of the config file: yaml, json or toml")
+
 	RootCmd.AddCommand(newProjectCmd)
 }
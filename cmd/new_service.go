@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/templates"
 	"github.com/zchase/jacuik/pkg/terminal"
 	"github.com/zchase/jacuik/pkg/utils"
 )
@@ -17,37 +18,72 @@ var newServiceCmd = &cobra.Command{
 	Run:   createNewService,
 }
 
+var (
+	serviceTemplateName string
+	serviceTemplateDir  string
+)
+
 func createNewService(cmd *cobra.Command, args []string) {
 	appConfig, configType, err := jacuik_config.ParseJacuikConfig()
 	utils.IfErrorExit(err, "couldn't successfully parse config")
 
+	err = appConfig.CheckWritable()
+	utils.IfErrorExit(err, "couldn't change config")
+
 	serviceName, err := terminal.NewTextPrompt("What is the name of your new service?", "")
 	utils.IfErrorExit(err, "couldn't set service name")
 
-	public, err := terminal.NewChoicePrompt("Is this a public service?", []string{"true", "false"})
-	utils.IfErrorExit(err, "couldn't set service public setting")
+	projectDirectory, err := appConfig.ProjectDirectory()
+	utils.IfErrorExit(err, "couldn't find project directory")
 
-	// Create the service directory and Dockerfile
-	wd, err := os.Getwd()
-	utils.IfErrorExit(err, "couldn't get current working directory")
+	// Pick the template to scaffold the service from.
+	templateDir := serviceTemplateDir
+	if templateDir == "" {
+		templateDir = filepath.Join(projectDirectory, templates.DefaultUserTemplateDirectory)
+	}
 
-	serviceDirPath := fmt.Sprintf("%s/%s", wd, serviceName)
-	err = utils.CreateDirectory(serviceDirPath)
-	utils.IfErrorExit(err, "couldn't create service directory")
+	templateName := serviceTemplateName
+	if templateName == "" {
+		availableTemplates, err := templates.ListServiceTemplates(templateDir)
+		utils.IfErrorExit(err, "couldn't list service templates")
 
-	dockerfilePath := fmt.Sprintf("%s/Dockerfile", serviceDirPath)
-	err = utils.WriteFile(dockerfilePath, "")
-	utils.IfErrorExit(err, "couldn't create service Dockerfile")
+		var templateNames []string
+		for _, t := range availableTemplates {
+			templateNames = append(templateNames, t.Name)
+		}
 
-	isServicePublic := true
-	if public == "false" {
-		isServicePublic = false
+		templateName, err = terminal.NewChoicePrompt("Which template would you like to use?", templateNames)
+		utils.IfErrorExit(err, "couldn't set service template")
 	}
 
+	serviceTemplate, err := templates.GetServiceTemplate(templateName, templateDir)
+	utils.IfErrorExit(err, "couldn't load service template")
+
+	// Create the service directory and render the template into it.
+	serviceDirPath := filepath.Join(projectDirectory, serviceName)
+	err = utils.CreateDirectory(serviceDirPath)
+	utils.IfErrorExit(err, "couldn't create service directory")
+
+	err = serviceTemplate.Render(serviceDirPath, templates.TemplateData{
+		ServiceName: serviceName,
+		ProjectName: appConfig.Name,
+		Port:        serviceTemplate.Port,
+	})
+	utils.IfErrorExit(err, "couldn't render service template")
+
 	newService := jacuik_config.ServiceConfig{
 		Name:             serviceName,
-		PathToDockerfile: ".",
-		Public:           isServicePublic,
+		Kind:             serviceTemplate.Kind,
+		PathToDockerfile: fmt.Sprintf("./%s", serviceName),
+		Port:             serviceTemplate.Port,
+	}
+
+	// Workers don't receive traffic, so they are never public.
+	if serviceTemplate.Kind != jacuik_config.ServiceKindWorker {
+		public, err := terminal.NewChoicePrompt("Is this a public service?", []string{"true", "false"})
+		utils.IfErrorExit(err, "couldn't set service public setting")
+
+		newService.Public = public == "true"
 	}
 
 	appConfig.AddService(newService)
@@ -59,5 +95,8 @@ func createNewService(cmd *cobra.Command, args []string) {
 }
 
 func init() {
+	newServiceCmd.Flags().StringVarP(&serviceTemplateName, "template", "t", "", "the template to scaffold the service from")
+	newServiceCmd.Flags().StringVar(&serviceTemplateDir, "template-dir", "", "a directory of user defined service templates (default .jacuik/templates)")
+
 	RootCmd.AddCommand(newServiceCmd)
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 	"github.com/zchase/jacuik/pkg/infrastructure"
@@ -10,11 +11,31 @@ import (
 	"github.com/zchase/jacuik/pkg/utils"
 )
 
+// infrastructureProjectName is the name of the Pulumi project the
+// application is deployed with.
+const infrastructureProjectName = "jacuik-demo"
+
+// newProvider returns the provider the config selects, exiting if it is
+// unknown.
+func newProvider(config *jacuik_config.AppConfig) infrastructure.Provider {
+	provider, err := infrastructure.NewProvider(infrastructureProjectName, config)
+	utils.IfErrorExit(err, "couldn't create provider")
+
+	return provider
+}
+
 var previewCmd = &cobra.Command{
 	Use:   "preview",
 	Short: "Preview a deployment.",
-	Long:  `Preview a deployment.`,
-	Run:   preview,
+	Long: `Preview a deployment.
+
+Once the preview finishes the plan is shown with the number of resources
+to create, update, replace and delete. Select a resource and press enter
+to see the properties that change and why it would be replaced.
+
+With --save-plan the plan is saved to a file for review, ` + "`jacuik up --plan`" + `
+then makes exactly those changes.`,
+	Run: preview,
 }
 
 func preview(cmd *cobra.Command, args []string) {
@@ -46,16 +67,41 @@ func preview(cmd *cobra.Command, args []string) {
 
 	fmt.Print("Preview of application updates:\n\n")
 
+	jacuik_config.RegisterInterpolationProvider("stack", infrastructure.StackOutputProvider(infrastructureProjectName))
+
 	config, _, err := jacuik_config.ParseJacuikConfig()
 	utils.IfErrorExit(err, "couldn't parse config")
 
-	infra := infrastructure.NewInfrastructureHandler("jacuik-demo", config)
+	infra := newProvider(config)
+
+	handler := infra.Preview
+	planSaved := false
+	if previewPlanPath != "" {
+		infra, ok := infra.(infrastructure.PlanProvider)
+		if !ok {
+			utils.ThrowError(fmt.Sprintf("The [%s] provider can't save plans.\n", config.GetProvider()))
+		}
+
+		handler = func(writer io.Writer) (*infrastructure.PreviewSummary, error) {
+			summary, err := infra.PreviewPlan(writer, previewPlanPath)
+			planSaved = err == nil
+			return summary, err
+		}
+	}
 
-	view := terminal.NewView(infra.Preview)
+	view := terminal.NewPreviewView(handler)
 	err = view.Start()
 	utils.IfErrorExit(err, "error running preview")
+
+	if planSaved {
+		fmt.Printf("✅ Plan saved to %s. Run `jacuik up --plan %s` to apply it.\n", previewPlanPath, previewPlanPath)
+	}
 }
 
+var previewPlanPath string
+
 func init() {
+	previewCmd.Flags().StringVar(&previewPlanPath, "save-plan", "", "save the plan to a file so `jacuik up --plan` can apply exactly these changes")
+
 	RootCmd.AddCommand(previewCmd)
 }
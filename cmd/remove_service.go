@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/terminal"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var removeServiceCmd = &cobra.Command{
+	Use:   "remove-service [name]",
+	Short: "Remove a service.",
+	Long:  `Remove a service from a Jacuik project, optionally deleting its directory.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   removeService,
+}
+
+var deleteServiceDirectory bool
+
+func removeService(cmd *cobra.Command, args []string) {
+	serviceName := args[0]
+
+	appConfig, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't successfully parse config")
+
+	err = appConfig.CheckWritable()
+	utils.IfErrorExit(err, "couldn't change config")
+
+	// Check the directory can be deleted before anything is changed.
+	serviceDirPath := ""
+	if deleteServiceDirectory {
+		serviceDirPath, err = appConfig.DeletableServiceDirectory(serviceName)
+		utils.IfErrorExit(err, "couldn't delete service directory")
+	}
+
+	err = appConfig.RemoveService(serviceName)
+	utils.IfErrorExit(err, "couldn't remove service")
+
+	if serviceDirPath != "" {
+		confirm, err := terminal.NewChoicePrompt(fmt.Sprintf("Delete %s and everything in it?", serviceDirPath), []string{"no", "yes"})
+		utils.IfErrorExit(err, "couldn't confirm directory deletion")
+
+		if confirm != "yes" {
+			serviceDirPath = ""
+		}
+	}
+
+	// The directory is only deleted once the config no longer points at it.
+	err = appConfig.SaveConfigFile()
+	utils.IfErrorExit(err, "couldn't update config file")
+
+	if serviceDirPath != "" {
+		err = os.RemoveAll(serviceDirPath)
+		utils.IfErrorExit(err, "couldn't delete service directory")
+	}
+
+	fmt.Println("✅ Service removed.")
+}
+
+func init() {
+	removeServiceCmd.Flags().BoolVar(&deleteServiceDirectory, "delete-directory", false, "delete the service's directory after confirmation")
+
+	RootCmd.AddCommand(removeServiceCmd)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/terminal"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var renameServiceCmd = &cobra.Command{
+	Use:   "rename-service [name] [new-name]",
+	Short: "Rename a service.",
+	Long:  `Rename a service in a Jacuik project along with its directory.`,
+	Args:  cobra.ExactArgs(2),
+	Run:   renameService,
+}
+
+func renameService(cmd *cobra.Command, args []string) {
+	oldName := args[0]
+	newName := args[1]
+
+	appConfig, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't successfully parse config")
+
+	err = appConfig.CheckWritable()
+	utils.IfErrorExit(err, "couldn't change config")
+
+	// Renaming in memory first checks that the service exists and that the
+	// new name is valid before anything is asked or changed on disk.
+	err = appConfig.RenameService(oldName, newName)
+	utils.IfErrorExit(err, "couldn't rename service")
+
+	// The cloud resources for a service are named after it so renaming
+	// a deployed service replaces them.
+	fmt.Printf("⚠️  Renaming [%s] to [%s] will replace its cloud resources on the next deployment.\n\n", oldName, newName)
+	confirm, err := terminal.NewChoicePrompt("Do you want to continue?", []string{"no", "yes"})
+	utils.IfErrorExit(err, "couldn't confirm rename")
+	if confirm != "yes" {
+		fmt.Println("Rename cancelled.")
+		return
+	}
+
+	// Only move the directory when it is named after the service. It is
+	// moved before the config is saved, which checks the new path, and moved
+	// back when the config can't be saved.
+	oldPath, newPath := "", ""
+	svc, _ := appConfig.GetService(newName)
+	if filepath.Base(filepath.Clean(svc.PathToDockerfile)) == oldName {
+		projectDirectory, err := appConfig.ProjectDirectory()
+		utils.IfErrorExit(err, "couldn't find project directory")
+
+		oldPath = filepath.Join(projectDirectory, svc.PathToDockerfile)
+		newPath = filepath.Join(filepath.Dir(oldPath), newName)
+
+		if _, err := os.Stat(newPath); err == nil {
+			utils.ThrowError(fmt.Sprintf("Directory [%s] already exists.\n", newPath))
+		}
+
+		relativePath, err := filepath.Rel(projectDirectory, newPath)
+		utils.IfErrorExit(err, "couldn't update service path")
+		svc.PathToDockerfile = fmt.Sprintf("./%s", filepath.ToSlash(relativePath))
+	}
+
+	if newPath != "" {
+		err = os.Rename(oldPath, newPath)
+		utils.IfErrorExit(err, fmt.Sprintf("couldn't move %s to %s", oldPath, newPath))
+	}
+
+	err = appConfig.SaveConfigFile()
+	if err != nil && newPath != "" {
+		if moveErr := os.Rename(newPath, oldPath); moveErr != nil {
+			utils.IfErrorExit(moveErr, fmt.Sprintf("couldn't move %s back to %s, move it to match the config", newPath, oldPath))
+		}
+	}
+	utils.IfErrorExit(err, "couldn't update config file")
+
+	fmt.Println("✅ Service renamed.")
+}
+
+func init() {
+	RootCmd.AddCommand(renameServiceCmd)
+}
@@ -3,8 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
 )
 
 var cfgFile string
@@ -14,6 +16,9 @@ var RootCmd = &cobra.Command{
 	Use:   "jacuik",
 	Short: "A CLI for building full stack applications.",
 	Long:  "A CLI for building full stack applications using containers on AWS.",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		warnIfConfigOutdated(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -26,5 +31,39 @@ func Execute() {
 }
 
 func init() {
+	cobra.OnInitialize(initConfig)
 
+	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "the project config file (default is the schema file in the current directory or one of its parents)")
+}
+
+// warnIfConfigOutdated lets the user know when the project config was written
+// in an older format and needs to be migrated.
+func warnIfConfigOutdated(cmd *cobra.Command) {
+	if cmd == migrateCmd {
+		return
+	}
+
+	filePath, configType, err := jacuik_config.LocateConfigFile()
+	if err != nil {
+		return
+	}
+
+	file, err := jacuik_config.LoadConfigFile(filePath, configType)
+	if err != nil {
+		return
+	}
+
+	version, err := file.Version()
+	if err != nil || version >= jacuik_config.CurrentConfigVersion {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠️  %s uses config version %d, the current version is %d. Run `jacuik migrate` to upgrade it.\n\n", filepath.Base(filePath), version, jacuik_config.CurrentConfigVersion)
+}
+
+// initConfig points the config package at the file passed with --config.
+func initConfig() {
+	if cfgFile != "" {
+		jacuik_config.SetConfigFile(cfgFile)
+	}
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Work with the JSON Schema for the project config.",
+	Long:  `Work with the JSON Schema describing schema.yaml, schema.json and schema.toml.`,
+}
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the JSON Schema.",
+	Long:  `Export the JSON Schema for the project config so editors can validate and autocomplete it.`,
+	Run:   exportSchema,
+}
+
+var schemaOutputPath string
+
+func exportSchema(cmd *cobra.Command, args []string) {
+	if schemaOutputPath != "" {
+		err := jacuik_config.WriteJSONSchemaFile(schemaOutputPath)
+		utils.IfErrorExit(err, "couldn't write JSON Schema")
+
+		fmt.Printf("✅ JSON Schema written to %s.\n", schemaOutputPath)
+		return
+	}
+
+	contents, err := jacuik_config.MarshalJSONSchema()
+	utils.IfErrorExit(err, "couldn't generate JSON Schema")
+
+	fmt.Print(string(contents))
+}
+
+func init() {
+	schemaExportCmd.Flags().StringVarP(&schemaOutputPath, "output", "o", "", "write the JSON Schema to a file instead of stdout")
+
+	schemaCmd.AddCommand(schemaExportCmd)
+	RootCmd.AddCommand(schemaCmd)
+}
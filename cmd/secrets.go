@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/infrastructure"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage how the stack's secrets are encrypted.",
+	Long: `Manage how the stack's secrets are encrypted.
+
+The secrets provider is set with secretsProvider in the config: default,
+passphrase or a key URL like awskms://alias/my-key?region=us-west-2. A
+passphrase is read from PULUMI_CONFIG_PASSPHRASE.`,
+}
+
+var secretsRotateProviderCmd = &cobra.Command{
+	Use:   "rotate-provider",
+	Short: "Re-encrypt the stack's secrets with the provider in the config.",
+	Long: `Re-encrypt the stack's secrets with the secrets provider in the config.
+
+Run it after changing secretsProvider, deployments are refused until the
+stack's secrets are encrypted with the provider in the config.`,
+	Args: cobra.NoArgs,
+	Run:  rotateSecretsProvider,
+}
+
+func rotateSecretsProvider(cmd *cobra.Command, args []string) {
+	config, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	infra, ok := newProvider(config).(infrastructure.SecretsProvider)
+	if !ok {
+		utils.ThrowError(fmt.Sprintf("The [%s] provider has no secrets to rotate.\n", config.GetProvider()))
+	}
+
+	err = infra.RotateSecretsProvider(os.Stdout)
+	utils.IfErrorExit(err, "couldn't rotate the secrets provider")
+
+	fmt.Printf("✅ The stack's secrets are now encrypted with %s.\n", config.SecretsProvider)
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsRotateProviderCmd)
+
+	RootCmd.AddCommand(secretsCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/infrastructure"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var servicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "List the services in a project.",
+	Long:  `List the services declared in a Jacuik project along with their deployed status.`,
+	Run:   listServices,
+}
+
+func listServices(cmd *cobra.Command, args []string) {
+	jacuik_config.RegisterInterpolationProvider("stack", infrastructure.StackOutputProvider(infrastructureProjectName))
+
+	config, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	statuses := make(map[string]infrastructure.ServiceStatus)
+	if infra, ok := newProvider(config).(infrastructure.ServiceStatusProvider); ok {
+		statuses, err = infra.ServiceStatuses()
+		utils.IfErrorExit(err, "couldn't read deployed services")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tPUBLIC\tPORT\tIMAGE TAG\tTASKS (DESIRED/RUNNING)\tURL\tSTATUS")
+
+	for _, svc := range config.Services {
+		port := "-"
+		if svc.GetKind() != jacuik_config.ServiceKindWorker {
+			port = strconv.Itoa(svc.GetPort())
+		}
+
+		status, deployed := statuses[svc.Name]
+		if !deployed {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t-\t-\t-\t%s\n", svc.Name, svc.GetKind(), svc.Public, port, "not deployed")
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\t%s\t%s\n", svc.Name, svc.GetKind(), svc.Public, port, valueOrDash(status.ImageTag()), formatTaskCounts(status), valueOrDash(status.URL), formatStatus("deployed", status))
+	}
+
+	// Services that are still live but have been removed from the config.
+	var undeclared []string
+	for name := range statuses {
+		if _, ok := config.GetService(name); !ok {
+			undeclared = append(undeclared, name)
+		}
+	}
+	sort.Strings(undeclared)
+
+	for _, name := range undeclared {
+		status := statuses[name]
+		fmt.Fprintf(w, "%s\t-\t-\t-\t%s\t%s\t%s\t%s\n", name, valueOrDash(status.ImageTag()), formatTaskCounts(status), valueOrDash(status.URL), formatStatus("not in schema", status))
+	}
+
+	err = w.Flush()
+	utils.IfErrorExit(err, "couldn't print services")
+
+	// Every service shares the same failure so it is only explained once.
+	for _, status := range statuses {
+		if status.RunningCountError != nil {
+			fmt.Printf("\n⚠️  Couldn't count the running tasks: %s\n", status.RunningCountError)
+			break
+		}
+	}
+}
+
+func formatStatus(state string, status infrastructure.ServiceStatus) string {
+	if status.RunningCountError != nil {
+		return fmt.Sprintf("%s, running tasks unknown", state)
+	}
+
+	return state
+}
+
+func formatTaskCounts(status infrastructure.ServiceStatus) string {
+	running := "?"
+	if status.RunningCount >= 0 {
+		running = strconv.Itoa(status.RunningCount)
+	}
+
+	return fmt.Sprintf("%d/%s", status.DesiredCount, running)
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func init() {
+	RootCmd.AddCommand(servicesCmd)
+}
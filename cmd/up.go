@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/infrastructure"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/terminal"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var upCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Deploy the application.",
+	Long: `Deploy the application.
+
+With --plan only the changes in a plan saved by ` + "`jacuik preview --save-plan`" + `
+are made. The deployment is refused if the config, the service sources or
+the stack changed since the plan was made.`,
+	Run: up,
+}
+
+var upPlanPath string
+
+func up(cmd *cobra.Command, args []string) {
+	jacuik_config.RegisterInterpolationProvider("stack", infrastructure.StackOutputProvider(infrastructureProjectName))
+
+	config, _, err := jacuik_config.ParseJacuikConfig()
+	utils.IfErrorExit(err, "couldn't parse config")
+
+	infra := newProvider(config)
+
+	handler := infra.Update
+	if upPlanPath != "" {
+		infra, ok := infra.(infrastructure.PlanProvider)
+		if !ok {
+			utils.ThrowError(fmt.Sprintf("The [%s] provider can't apply plans.\n", config.GetProvider()))
+		}
+
+		plan, err := infrastructure.ReadPlanFile(upPlanPath)
+		utils.IfErrorExit(err, "couldn't read plan")
+
+		err = infra.CheckPlan(plan)
+		utils.IfErrorExit(err, "can't apply plan")
+
+		fmt.Printf("Applying plan from %s: %s\n\n", plan.CreatedAt, plan.Summary.Text())
+		handler = func(writer io.Writer) error {
+			return infra.UpdatePlan(writer, plan)
+		}
+	} else {
+		fmt.Print("Deploying application updates:\n\n")
+	}
+
+	view := terminal.NewView(handler)
+	err = view.Start()
+	utils.IfErrorExit(err, "error running update")
+}
+
+func init() {
+	upCmd.Flags().StringVar(&upPlanPath, "plan", "", "a plan saved by `jacuik preview --save-plan` to apply")
+
+	RootCmd.AddCommand(upCmd)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the project config.",
+	Long:  `Validate the project config file and report every problem found in it without touching the cloud.`,
+	Run:   validate,
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	filePath, _, err := jacuik_config.LocateConfigFile()
+	utils.IfErrorExit(err, "couldn't find config file")
+
+	validationErrors, err := jacuik_config.ValidateConfigFile(filePath)
+	utils.IfErrorExit(err, "couldn't validate config file")
+
+	if len(validationErrors) > 0 {
+		for _, validationError := range validationErrors {
+			fmt.Println(validationError)
+		}
+
+		utils.ThrowError(fmt.Sprintf("\n%d problem(s) found.", len(validationErrors)))
+	}
+
+	fmt.Printf("✅ %s is valid.\n", filepath.Base(filePath))
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+}
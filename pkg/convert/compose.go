@@ -0,0 +1,175 @@
+package convert
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ComposeFile is a docker-compose.yaml.
+type ComposeFile struct {
+	Services map[string]*ComposeService `yaml:"services"`
+	Volumes  map[string]*ComposeVolume  `yaml:"volumes,omitempty"`
+}
+
+// ComposeService is a service in a docker-compose.yaml.
+type ComposeService struct {
+	Image       string            `yaml:"image,omitempty"`
+	Build       string            `yaml:"build,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Expose      []string          `yaml:"expose,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+}
+
+// ComposeVolume is a named volume in a docker-compose.yaml, the defaults
+// are used for all of them.
+type ComposeVolume struct{}
+
+// composeEnvExpressionPattern matches the ${env:NAME} expressions left in
+// the env of a service once its $ are escaped for compose.
+var composeEnvExpressionPattern = regexp.MustCompile(`\$\$\{env:([^}]*)\}`)
+
+// composeValue escapes the $ of an environment value, compose would
+// interpolate them, except in ${env:NAME} expressions that become ${NAME}
+// for compose to fill in from the environment or a .env file.
+func composeValue(value string) string {
+	escaped := strings.ReplaceAll(value, "$", "$$")
+	return composeEnvExpressionPattern.ReplaceAllString(escaped, "$${$1}")
+}
+
+// ToCompose converts the project into a docker-compose.yaml written to
+// outputDirectory. Public web services are published on the host starting
+// at hostPort, resources are replaced by local stand-ins.
+func ToCompose(config *jacuik_config.AppConfig, outputDirectory string, hostPort int) (*ComposeFile, error) {
+	projectDirectory, err := config.ProjectDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	compose := &ComposeFile{
+		Services: make(map[string]*ComposeService),
+		Volumes:  make(map[string]*ComposeVolume),
+	}
+
+	for _, svc := range config.Services {
+		buildContext, err := filepath.Rel(outputDirectory, filepath.Join(projectDirectory, svc.PathToDockerfile))
+		if err != nil {
+			return nil, err
+		}
+
+		environment, err := ServiceEnvironment(config, svc)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range environment {
+			environment[name] = composeValue(value)
+		}
+
+		service := &ComposeService{
+			Build:       filepath.ToSlash(buildContext),
+			Environment: environment,
+			DependsOn:   svc.DependsOn,
+		}
+
+		if !strings.HasPrefix(service.Build, ".") {
+			service.Build = "./" + service.Build
+		}
+
+		if svc.GetKind() != jacuik_config.ServiceKindWorker {
+			if svc.Public {
+				service.Ports = []string{fmt.Sprintf("%d:%d", hostPort, svc.GetPort())}
+				hostPort++
+			} else {
+				service.Expose = []string{fmt.Sprint(svc.GetPort())}
+			}
+		}
+
+		compose.Services[svc.Name] = service
+	}
+
+	for _, resource := range config.Resources {
+		standIn, err := StandInFor(resource)
+		if err != nil {
+			return nil, err
+		}
+
+		service := &ComposeService{
+			Image:       standIn.Image,
+			Expose:      []string{fmt.Sprint(standIn.Port)},
+			Environment: standIn.Environment,
+		}
+
+		if standIn.DataPath != "" {
+			volume := resource.Name + "-data"
+			service.Volumes = []string{fmt.Sprintf("%s:%s", volume, standIn.DataPath)}
+			compose.Volumes[volume] = &ComposeVolume{}
+		}
+
+		compose.Services[resource.Name] = service
+	}
+
+	return compose, nil
+}
+
+// Marshal returns the docker-compose.yaml contents. Services are written in
+// the order they are declared in config, services before resources.
+func (c *ComposeFile) Marshal(config *jacuik_config.AppConfig) ([]byte, error) {
+	var document yaml.Node
+	err := document.Encode(c)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make(map[string]int)
+	for i, svc := range config.Services {
+		order[svc.Name] = i
+	}
+	for i, resource := range config.Resources {
+		order[resource.Name] = len(config.Services) + i
+	}
+
+	root := &document
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value != "services" {
+			continue
+		}
+
+		services := root.Content[i+1]
+		pairs := make([][2]*yaml.Node, 0, len(services.Content)/2)
+		for j := 0; j < len(services.Content); j += 2 {
+			pairs = append(pairs, [2]*yaml.Node{services.Content[j], services.Content[j+1]})
+		}
+		sort.SliceStable(pairs, func(x, y int) bool {
+			return order[pairs[x][0].Value] < order[pairs[y][0].Value]
+		})
+
+		services.Content = services.Content[:0]
+		for _, pair := range pairs {
+			services.Content = append(services.Content, pair[0], pair[1])
+			quotePorts(pair[1])
+		}
+	}
+
+	return yaml.Marshal(&document)
+}
+
+// quotePorts quotes the port mappings of a service, YAML 1.1 parsers read
+// some unquoted mappings like 22:22 as numbers.
+func quotePorts(service *yaml.Node) {
+	for i := 0; i < len(service.Content); i += 2 {
+		if service.Content[i].Value != "ports" {
+			continue
+		}
+
+		for _, port := range service.Content[i+1].Content {
+			port.Style = yaml.DoubleQuotedStyle
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// importedResourceImages are the images that are imported as a resource
+// instead of a service, by image name without the registry and the tag.
+var importedResourceImages = map[string]string{
+	"postgres":                      jacuik_config.ResourceTypePostgres,
+	"postgis/postgis":               jacuik_config.ResourceTypePostgres,
+	"redis":                         jacuik_config.ResourceTypeRedis,
+	"bitnami/redis":                 jacuik_config.ResourceTypeRedis,
+	"rabbitmq":                      jacuik_config.ResourceTypeQueue,
+	"softwaremill/elasticmq":        jacuik_config.ResourceTypeQueue,
+	"softwaremill/elasticmq-native": jacuik_config.ResourceTypeQueue,
+}
+
+// ignoredComposeKeys map to nothing in the config but are handled by the
+// cloud, so leaving them out isn't worth a warning.
+var ignoredComposeKeys = map[string]bool{
+	"container_name": true,
+	"restart":        true,
+}
+
+// composeVariable matches a variable in a compose file, ${NAME}, ${NAME:-default}
+// or $NAME. $$ is an escaped $.
+var composeVariable = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)([:?+-][^}]*)?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// composeImport is the state of an import, the warnings are collected as
+// the compose file is read.
+type composeImport struct {
+	composeDirectory string
+	outputDirectory  string
+	warnings         []string
+	// names are the config names of the compose services.
+	names map[string]string
+}
+
+func (c *composeImport) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// FromCompose reads a docker-compose.yaml and returns the config of a
+// project made from it, with service paths relative to outputDirectory.
+// What can't be carried over is returned as warnings.
+func FromCompose(composePath, outputDirectory string) (*jacuik_config.AppConfig, []string, error) {
+	contents, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var document yaml.Node
+	err = yaml.Unmarshal(contents, &document)
+	if err != nil {
+		return nil, nil, fmt.Errorf("The compose file [%s] is not valid YAML: %s.", composePath, err)
+	}
+
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("The compose file [%s] is empty.", composePath)
+	}
+
+	composeDirectory, err := filepath.Abs(filepath.Dir(composePath))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &composeImport{
+		composeDirectory: composeDirectory,
+		outputDirectory:  outputDirectory,
+		names:            make(map[string]string),
+	}
+
+	config := &jacuik_config.AppConfig{
+		Version:     jacuik_config.CurrentConfigVersion,
+		Name:        kubernetesName(filepath.Base(composeDirectory)),
+		Description: fmt.Sprintf("Imported from %s.", filepath.Base(composePath)),
+	}
+
+	root := document.Content[0]
+	var services *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		switch key {
+		case "name":
+			config.Name = kubernetesName(value.Value)
+		case "services":
+			services = value
+		case "version", "volumes":
+			// The version is obsolete and named volumes only matter to the
+			// services using them.
+		default:
+			c.warn("%s isn't supported and was left out.", key)
+		}
+	}
+
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("The compose file [%s] has no services.", composePath)
+	}
+
+	// Valid names are kept, renamed services are numbered when they would
+	// get the name of another service, like my_api next to my-api.
+	taken := make(map[string]bool)
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name := services.Content[i].Value
+		if kubernetesName(name) == name {
+			c.names[name] = name
+			taken[name] = true
+		}
+	}
+
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name := services.Content[i].Value
+		if _, ok := c.names[name]; ok {
+			continue
+		}
+
+		configName := kubernetesName(name)
+		for number := 2; taken[configName]; number++ {
+			configName = fmt.Sprintf("%s-%d", kubernetesName(name), number)
+		}
+		taken[configName] = true
+
+		c.names[name] = configName
+		c.warn("Service %s was renamed to %s, names may only contain lowercase letters, numbers and dashes.", name, configName)
+	}
+
+	// Dependencies on services that weren't imported are dropped.
+	imported := make(map[string]bool)
+	var dependsOn [][]string
+
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name, node := services.Content[i].Value, services.Content[i+1]
+
+		service, resource, dependencies, err := c.importService(name, node)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case service != nil:
+			config.Services = append(config.Services, *service)
+			dependsOn = append(dependsOn, dependencies)
+			imported[service.Name] = true
+		case resource != nil:
+			config.Resources = append(config.Resources, *resource)
+			imported[resource.Name] = true
+		}
+	}
+
+	for i := range config.Services {
+		for _, dependency := range dependsOn[i] {
+			if !imported[dependency] {
+				c.warn("Service %s depends on %s, which wasn't imported.", config.Services[i].Name, dependency)
+				continue
+			}
+
+			config.Services[i].DependsOn = append(config.Services[i].DependsOn, dependency)
+		}
+	}
+
+	return config, c.warnings, nil
+}
+
+// importService converts a compose service into a service, or a resource
+// when it runs a known database, cache or queue image. Neither is returned
+// when the service can't be imported.
+func (c *composeImport) importService(name string, node *yaml.Node) (*jacuik_config.ServiceConfig, *jacuik_config.ResourceConfig, []string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil, nil, fmt.Errorf("Service [%s] in the compose file must be a mapping.", name)
+	}
+
+	fields := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fields[node.Content[i].Value] = node.Content[i+1]
+	}
+
+	configName := c.names[name]
+
+	build, hasBuild := fields["build"]
+	if !hasBuild {
+		image := ""
+		if imageNode, ok := fields["image"]; ok {
+			image = imageNode.Value
+		}
+
+		if resourceType, ok := importedResourceImages[imageName(image)]; ok {
+			if _, ok := fields["environment"]; ok {
+				c.warn("The environment of %s was left out, its stand-in is configured by jacuik.", name)
+			}
+
+			return nil, &jacuik_config.ResourceConfig{Name: configName, Type: resourceType}, nil, nil
+		}
+
+		c.warn("Service %s runs the prebuilt image %s and was left out, services are built from a Dockerfile.", name, image)
+		return nil, nil, nil, nil
+	}
+
+	service := &jacuik_config.ServiceConfig{Name: configName}
+
+	buildPath, err := c.buildPath(name, build)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	service.PathToDockerfile = buildPath
+
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var dependencies []string
+	for _, key := range keys {
+		value := fields[key]
+		switch key {
+		case "build":
+		case "image":
+			// The image is pushed to the project's repository instead.
+		case "ports":
+			c.importPorts(name, service, value, true)
+		case "expose":
+			// Published ports win over exposed ones.
+			if _, hasPorts := fields["ports"]; !hasPorts {
+				c.importPorts(name, service, value, false)
+			}
+		case "environment":
+			service.Env = c.importEnvironment(name, value)
+		case "depends_on":
+			for _, dependency := range composeNames(value) {
+				dependencyName, ok := c.names[dependency]
+				if !ok {
+					dependencyName = kubernetesName(dependency)
+				}
+				dependencies = append(dependencies, dependencyName)
+			}
+		case "volumes":
+			c.warn("The volumes of %s were left out, services don't keep data between deployments. Use a resource instead.", name)
+		default:
+			if !ignoredComposeKeys[key] {
+				c.warn("%s of %s isn't supported and was left out.", key, name)
+			}
+		}
+	}
+
+	// Without a port the service doesn't receive traffic.
+	if _, hasPorts := fields["ports"]; !hasPorts {
+		if _, hasExpose := fields["expose"]; !hasExpose {
+			service.Kind = jacuik_config.ServiceKindWorker
+		}
+	}
+
+	return service, nil, dependencies, nil
+}
+
+// buildPath returns the build context of a service relative to the output
+// directory.
+func (c *composeImport) buildPath(name string, build *yaml.Node) (string, error) {
+	buildContext := "."
+	switch build.Kind {
+	case yaml.ScalarNode:
+		buildContext = build.Value
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(build.Content); i += 2 {
+			key, value := build.Content[i].Value, build.Content[i+1]
+			switch key {
+			case "context":
+				buildContext = value.Value
+			case "dockerfile":
+				if value.Value != "Dockerfile" {
+					c.warn("Service %s is built from %s, rename it to Dockerfile in its build context.", name, value.Value)
+				}
+			default:
+				c.warn("build.%s of %s isn't supported and was left out.", key, name)
+			}
+		}
+	}
+
+	if strings.Contains(buildContext, "://") || strings.HasPrefix(buildContext, "git@") {
+		return "", fmt.Errorf("Service [%s] is built from the remote context [%s], clone it first.", name, buildContext)
+	}
+
+	relativePath, err := filepath.Rel(c.outputDirectory, filepath.Join(c.composeDirectory, buildContext))
+	if err != nil {
+		return "", err
+	}
+
+	relativePath = filepath.ToSlash(relativePath)
+	if !strings.HasPrefix(relativePath, ".") {
+		relativePath = "./" + relativePath
+	}
+
+	return relativePath, nil
+}
+
+// importPorts sets the port of a service from its ports or expose. Ports
+// in ports make the service public, even without a host port Docker
+// publishes them on a random one.
+func (c *composeImport) importPorts(name string, service *jacuik_config.ServiceConfig, ports *yaml.Node, published bool) {
+	if ports.Kind != yaml.SequenceNode || len(ports.Content) == 0 {
+		return
+	}
+
+	if len(ports.Content) > 1 {
+		c.warn("Service %s has %d ports, only the first is kept.", name, len(ports.Content))
+	}
+
+	port := ports.Content[0]
+	containerPort := ""
+
+	switch port.Kind {
+	case yaml.ScalarNode:
+		// [[ip:]published:]target[/protocol]
+		parts := strings.Split(strings.SplitN(port.Value, "/", 2)[0], ":")
+		containerPort = parts[len(parts)-1]
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(port.Content); i += 2 {
+			if port.Content[i].Value == "target" {
+				containerPort = port.Content[i+1].Value
+			}
+		}
+	}
+
+	var value int
+	_, err := fmt.Sscanf(containerPort, "%d", &value)
+	if err != nil || fmt.Sprint(value) != containerPort {
+		c.warn("The port %s of %s isn't supported and was left out.", containerPort, name)
+		return
+	}
+
+	if value != jacuik_config.DefaultServicePort {
+		service.Port = value
+	}
+	service.Public = published
+}
+
+// importEnvironment converts the environment of a service. Variables taken
+// from the shell become ${env:NAME}.
+func (c *composeImport) importEnvironment(name string, environment *yaml.Node) map[string]string {
+	result := make(map[string]string)
+
+	add := func(variable string, value *string) {
+		if value == nil {
+			result[variable] = fmt.Sprintf("${env:%s}", variable)
+			return
+		}
+
+		result[variable] = c.convertVariables(name, *value)
+	}
+
+	switch environment.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(environment.Content); i += 2 {
+			value := environment.Content[i+1]
+			if value.Tag == "!!null" {
+				add(environment.Content[i].Value, nil)
+			} else {
+				add(environment.Content[i].Value, &value.Value)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range environment.Content {
+			variable, value, ok := strings.Cut(item.Value, "=")
+			if ok {
+				add(variable, &value)
+			} else {
+				add(variable, nil)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// convertVariables rewrites the compose variables in value as ${env:NAME}.
+// An escaped $ stays escaped only where the config would read it as a
+// variable, before a {.
+func (c *composeImport) convertVariables(name, value string) string {
+	var result strings.Builder
+	last := 0
+	for _, match := range composeVariable.FindAllStringSubmatchIndex(value, -1) {
+		result.WriteString(value[last:match[0]])
+		last = match[1]
+
+		expression := value[match[0]:match[1]]
+		if expression == "$$" {
+			if strings.HasPrefix(value[last:], "{") {
+				result.WriteString("$$")
+			} else {
+				result.WriteString("$")
+			}
+			continue
+		}
+
+		groups := composeVariable.FindStringSubmatch(expression)
+		if groups[2] != "" {
+			c.warn("The default or check in %s of %s isn't supported, the value is taken from the environment as is.", expression, name)
+		}
+
+		result.WriteString(fmt.Sprintf("${env:%s}", groups[1]+groups[3]))
+	}
+	result.WriteString(value[last:])
+
+	return result.String()
+}
+
+// composeNames returns the names in a depends_on, which is a list of names
+// or a mapping from names to conditions.
+func composeNames(node *yaml.Node) []string {
+	var names []string
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			names = append(names, item.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			names = append(names, node.Content[i].Value)
+		}
+	}
+
+	return names
+}
+
+// imageName returns the name of an image without the registry and the tag,
+// library images lose the library/ prefix.
+func imageName(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+
+	parts := strings.Split(image, "/")
+	if len(parts) > 1 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		parts = parts[1:]
+	}
+
+	return strings.TrimPrefix(path.Join(parts...), "library/")
+}
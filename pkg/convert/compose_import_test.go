@@ -0,0 +1,229 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestImportPorts(t *testing.T) {
+	tests := []struct {
+		name      string
+		ports     string
+		published bool
+		port      int
+		public    bool
+		warnings  []string
+	}{
+		{name: "published", ports: `["8080:3000"]`, published: true, port: 3000, public: true},
+		{name: "published with an ip and a protocol", ports: `["127.0.0.1:8080:3000/tcp"]`, published: true, port: 3000, public: true},
+		{name: "random host port", ports: `["3000"]`, published: true, port: 3000, public: true},
+		{name: "unquoted", ports: `[3000]`, published: true, port: 3000, public: true},
+		{name: "default port", ports: `["80:80"]`, published: true, port: 0, public: true},
+		{name: "long syntax", ports: `[{target: 3000, published: 8080}]`, published: true, port: 3000, public: true},
+		{name: "long syntax without a host port", ports: `[{target: 3000}]`, published: true, port: 3000, public: true},
+		{name: "exposed", ports: `["3000"]`, published: false, port: 3000, public: false},
+		{name: "several", ports: `["3000", "9229"]`, published: true, port: 3000, public: true, warnings: []string{"Service api has 2 ports, only the first is kept."}},
+		{name: "range", ports: `["3000-3005"]`, published: true, warnings: []string{"The port 3000-3005 of api isn't supported and was left out."}},
+		{name: "empty", ports: `[]`, published: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(test.ports), &node); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &composeImport{}
+			service := &jacuik_config.ServiceConfig{Name: "api"}
+			c.importPorts("api", service, node.Content[0], test.published)
+
+			if service.Port != test.port || service.Public != test.public {
+				t.Errorf("got port %d and public %t, want %d and %t", service.Port, service.Public, test.port, test.public)
+			}
+			if !reflect.DeepEqual(c.warnings, test.warnings) {
+				t.Errorf("got warnings %q, want %q", c.warnings, test.warnings)
+			}
+		})
+	}
+}
+
+func TestConvertVariables(t *testing.T) {
+	tests := []struct {
+		value    string
+		want     string
+		warnings []string
+	}{
+		{value: "plain", want: "plain"},
+		{value: "${DATABASE_URL}", want: "${env:DATABASE_URL}"},
+		{value: "http://$HOST:${PORT}/", want: "http://${env:HOST}:${env:PORT}/"},
+		{value: "${LEVEL:-info}", want: "${env:LEVEL}", warnings: []string{"The default or check in ${LEVEL:-info} of api isn't supported, the value is taken from the environment as is."}},
+		{value: "${KEY?required}", want: "${env:KEY}", warnings: []string{"The default or check in ${KEY?required} of api isn't supported, the value is taken from the environment as is."}},
+		{value: "costs $$5", want: "costs $5"},
+		{value: "$${NOT_A_VARIABLE}", want: "$${NOT_A_VARIABLE}"},
+		{value: "$1", want: "$1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			c := &composeImport{}
+			if got := c.convertVariables("api", test.value); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if !reflect.DeepEqual(c.warnings, test.warnings) {
+				t.Errorf("got warnings %q, want %q", c.warnings, test.warnings)
+			}
+		})
+	}
+}
+
+func TestImageName(t *testing.T) {
+	tests := map[string]string{
+		"postgres":                          "postgres",
+		"postgres:14-alpine":                "postgres",
+		"library/redis:7":                   "redis",
+		"docker.io/library/postgres:14":     "postgres",
+		"postgis/postgis:14-3.3":            "postgis/postgis",
+		"ghcr.io/acme/api:v1":               "acme/api",
+		"localhost/redis":                   "redis",
+		"localhost:5000/bitnami/redis:7":    "bitnami/redis",
+		"redis@sha256:0123456789abcdef":     "redis",
+		"registry.example.com:5000/app:1.0": "app",
+		"":                                  "",
+	}
+
+	for image, want := range tests {
+		if got := imageName(image); got != want {
+			t.Errorf("imageName(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestFromCompose(t *testing.T) {
+	composeDirectory := t.TempDir()
+	composePath := filepath.Join(composeDirectory, "docker-compose.yaml")
+	err := os.WriteFile(composePath, []byte(`version: "3.9"
+name: Shop
+services:
+  web:
+    build: ./web
+    ports:
+      - "3000"
+    depends_on: [api]
+  api:
+    build:
+      context: ./api
+    expose: ["8080"]
+    environment:
+      DATABASE_URL: ${DATABASE_URL}
+      API_KEY:
+    depends_on:
+      db:
+        condition: service_healthy
+      mail:
+        condition: service_started
+  worker:
+    build: ./api
+    restart: always
+  db:
+    image: postgres:14
+    environment:
+      POSTGRES_PASSWORD: example
+  mail:
+    image: mailhog/mailhog
+volumes:
+  data: {}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, warnings, err := FromCompose(composePath, composeDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &jacuik_config.AppConfig{
+		Version:     jacuik_config.CurrentConfigVersion,
+		Name:        "shop",
+		Description: "Imported from docker-compose.yaml.",
+		Services: []jacuik_config.ServiceConfig{
+			{Name: "web", PathToDockerfile: "./web", Public: true, Port: 3000, DependsOn: []string{"api"}},
+			{Name: "api", PathToDockerfile: "./api", Port: 8080, DependsOn: []string{"db"}, Env: map[string]string{
+				"DATABASE_URL": "${env:DATABASE_URL}",
+				"API_KEY":      "${env:API_KEY}",
+			}},
+			{Name: "worker", PathToDockerfile: "./api", Kind: jacuik_config.ServiceKindWorker},
+		},
+		Resources: []jacuik_config.ResourceConfig{
+			{Name: "db", Type: jacuik_config.ResourceTypePostgres},
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got config:\n%+v\nwant:\n%+v", config, want)
+	}
+
+	wantWarnings := []string{
+		"The environment of db was left out, its stand-in is configured by jacuik.",
+		"Service mail runs the prebuilt image mailhog/mailhog and was left out, services are built from a Dockerfile.",
+		"Service api depends on mail, which wasn't imported.",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("got warnings:\n%q\nwant:\n%q", warnings, wantWarnings)
+	}
+}
+
+func TestFromComposeNameCollisions(t *testing.T) {
+	composeDirectory := t.TempDir()
+	composePath := filepath.Join(composeDirectory, "docker-compose.yaml")
+	err := os.WriteFile(composePath, []byte(`services:
+  my_api:
+    build: ./api
+    expose: ["80"]
+  my-api:
+    build: ./api
+    expose: ["80"]
+  My.API:
+    build: ./api
+    expose: ["80"]
+  web:
+    build: ./web
+    ports: ["80"]
+    depends_on: [my_api, My.API]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, warnings, err := FromCompose(composePath, composeDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The valid name is kept, the others are numbered in order.
+	var names []string
+	for _, svc := range config.Services {
+		names = append(names, svc.Name)
+	}
+	if want := []string{"my-api-2", "my-api", "my-api-3", "web"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got services %v, want %v", names, want)
+	}
+
+	web, _ := config.GetService("web")
+	if want := []string{"my-api-2", "my-api-3"}; !reflect.DeepEqual(web.DependsOn, want) {
+		t.Errorf("web depends on %v, want %v", web.DependsOn, want)
+	}
+
+	wantWarnings := []string{
+		"Service my_api was renamed to my-api-2, names may only contain lowercase letters, numbers and dashes.",
+		"Service My.API was renamed to my-api-3, names may only contain lowercase letters, numbers and dashes.",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("got warnings:\n%q\nwant:\n%q", warnings, wantWarnings)
+	}
+}
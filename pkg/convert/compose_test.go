@@ -0,0 +1,180 @@
+package convert
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zchase/jacuik/pkg/internal/testutil"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+func TestToCompose(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		paths  []string
+		// output is where the compose file is written, relative to the
+		// project.
+		output string
+		want   string
+	}{
+		{
+			name: "services and resources",
+			schema: `version: 2
+name: demo
+services:
+  - name: web
+    path: ./web
+    public: true
+    port: 3000
+    dependsOn: [api]
+  - name: api
+    path: ./api
+    public: true
+    dependsOn: [db, cache, jobs]
+    env:
+      LOG_LEVEL: debug
+      API_KEY: $${env:API_KEY}
+      PRICE: $5
+  - name: worker
+    path: ./api
+    kind: worker
+    dependsOn: [jobs]
+resources:
+  - name: db
+    type: postgres
+  - name: cache
+    type: redis
+  - name: jobs
+    type: queue
+`,
+			paths:  []string{"web", "api"},
+			output: ".",
+			want: `services:
+    web:
+        build: ./web
+        ports:
+            - "8080:3000"
+        environment:
+            API_URL: http://api:80
+            PORT: "3000"
+        depends_on:
+            - api
+    api:
+        build: ./api
+        ports:
+            - "8081:80"
+        environment:
+            API_KEY: ${API_KEY}
+            CACHE_URL: redis://cache:6379
+            DB_URL: postgres://postgres:postgres@db:5432/postgres
+            JOBS_URL: http://jobs:9324/queue/jobs
+            LOG_LEVEL: debug
+            PORT: "80"
+            PRICE: $$5
+        depends_on:
+            - db
+            - cache
+            - jobs
+    worker:
+        build: ./api
+        environment:
+            JOBS_URL: http://jobs:9324/queue/jobs
+            PORT: "80"
+        depends_on:
+            - jobs
+    db:
+        image: postgres:14-alpine
+        expose:
+            - "5432"
+        environment:
+            POSTGRES_PASSWORD: postgres
+            POSTGRES_USER: postgres
+        volumes:
+            - db-data:/var/lib/postgresql/data
+    cache:
+        image: redis:7-alpine
+        expose:
+            - "6379"
+        volumes:
+            - cache-data:/data
+    jobs:
+        image: softwaremill/elasticmq-native:1.3.9
+        expose:
+            - "9324"
+volumes:
+    cache-data: {}
+    db-data: {}
+`,
+		},
+		{
+			name: "private service written to a subdirectory",
+			schema: `version: 2
+name: demo
+services:
+  - name: api
+    path: .
+  - name: admin
+    path: ./admin
+    public: true
+    dependsOn: [api]
+`,
+			paths:  []string{".", "admin"},
+			output: "deploy",
+			want: `services:
+    api:
+        build: ..
+        expose:
+            - "80"
+        environment:
+            PORT: "80"
+    admin:
+        build: ../admin
+        ports:
+            - "8080:80"
+        environment:
+            API_URL: http://api:80
+            PORT: "80"
+        depends_on:
+            - api
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, projectDirectory := testutil.LoadConfig(t, test.schema, test.paths...)
+
+			compose, err := ToCompose(config, filepath.Join(projectDirectory, test.output), 8080)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			contents, err := compose.Marshal(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(contents) != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", contents, test.want)
+			}
+		})
+	}
+}
+
+func TestToComposeUnknownResource(t *testing.T) {
+	config, projectDirectory := testutil.LoadConfig(t, `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+`, "api")
+
+	config.Resources = append(config.Resources, jacuik_config.ResourceConfig{Name: "search", Type: "elasticsearch"})
+
+	_, err := ToCompose(config, projectDirectory, 8080)
+	want := "Resource [search] has an unknown type [elasticsearch]."
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,374 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// helmValues are the values.yaml of the chart. The templates are the same
+// for every project, everything that comes from the config is in here.
+type helmValues struct {
+	Image       helmImage              `yaml:"image"`
+	Ingress     helmIngress            `yaml:"ingress"`
+	Autoscaling helmAutoscaling        `yaml:"autoscaling"`
+	Resources   resourceRequirements   `yaml:"resources"`
+	Services    map[string]helmService `yaml:"services"`
+}
+
+type helmImage struct {
+	// Registry is prepended to the repository of each service.
+	Registry string `yaml:"registry"`
+	Tag      string `yaml:"tag"`
+}
+
+type helmIngress struct {
+	ClassName string `yaml:"className"`
+}
+
+type helmAutoscaling struct {
+	Enabled                        bool `yaml:"enabled"`
+	MinReplicas                    int  `yaml:"minReplicas"`
+	MaxReplicas                    int  `yaml:"maxReplicas"`
+	TargetCPUUtilizationPercentage int  `yaml:"targetCPUUtilizationPercentage"`
+}
+
+type helmService struct {
+	// Repository is the image without the registry and the tag. Stand-ins
+	// use Image instead.
+	Repository string            `yaml:"repository,omitempty"`
+	Image      string            `yaml:"image,omitempty"`
+	Port       int               `yaml:"port,omitempty"`
+	Public     bool              `yaml:"public,omitempty"`
+	Host       string            `yaml:"host,omitempty"`
+	Config     map[string]string `yaml:"config,omitempty"`
+	Secrets    map[string]string `yaml:"secrets,omitempty"`
+	// Env names the env of the service from the config. Its values aren't
+	// in the chart, they are set with envValues when it is installed.
+	Env      []string `yaml:"env,omitempty"`
+	StandIn  bool     `yaml:"standIn,omitempty"`
+	DataPath string   `yaml:"dataPath,omitempty"`
+}
+
+// productionEnvironments get more replicas in their values file.
+var productionEnvironments = map[string]bool{"prod": true, "production": true}
+
+// ToHelmChart converts the project into a Helm chart, the contents of each
+// file by its path in the chart. Every environment in options gets a
+// values-<environment>.yaml to use on top of values.yaml.
+func ToHelmChart(config *jacuik_config.AppConfig, options KubernetesOptions) (map[string][]byte, error) {
+	projectWorkloads, err := workloads(config, KubernetesOptions{Domain: options.Domain})
+	if err != nil {
+		return nil, err
+	}
+
+	values := helmValues{
+		Image: helmImage{Registry: options.Registry, Tag: options.Tag},
+		Autoscaling: helmAutoscaling{
+			Enabled:                        true,
+			MinReplicas:                    defaultMinReplicas,
+			MaxReplicas:                    defaultMaxReplicas,
+			TargetCPUUtilizationPercentage: defaultCPUUtilization,
+		},
+		Resources: resourceRequirements{
+			Requests: map[string]string{"cpu": defaultCPURequest, "memory": defaultMemoryRequest},
+		},
+		Services: make(map[string]helmService),
+	}
+
+	for _, w := range projectWorkloads {
+		service := helmService{
+			Port:     w.Port,
+			Public:   w.Public,
+			Host:     w.Host,
+			Config:   w.Config,
+			Secrets:  w.Secrets,
+			StandIn:  w.StandIn,
+			DataPath: w.DataPath,
+		}
+
+		for name := range w.Env {
+			service.Env = append(service.Env, name)
+		}
+		sort.Strings(service.Env)
+
+		if w.StandIn {
+			service.Image = w.Image
+		} else {
+			// The tag is left off, it comes from image.tag.
+			service.Repository = strings.TrimSuffix(w.Image, ":")
+		}
+
+		values.Services[w.Name] = service
+	}
+
+	name := kubernetesName(config.Name)
+	files := map[string][]byte{
+		"Chart.yaml":                []byte(fmt.Sprintf(helmChartFile, name, config.Description)),
+		"templates/_helpers.tpl":    []byte(helmHelpersTemplate),
+		"templates/configmap.yaml":  []byte(helmConfigMapTemplate),
+		"templates/secret.yaml":     []byte(helmSecretTemplate),
+		"templates/deployment.yaml": []byte(helmDeploymentTemplate),
+		"templates/service.yaml":    []byte(helmServiceTemplate),
+		"templates/ingress.yaml":    []byte(helmIngressTemplate),
+		"templates/hpa.yaml":        []byte(helmHorizontalPodAutoscalerTemplate),
+		"templates/NOTES.txt":       []byte(helmNotesTemplate),
+	}
+
+	contents, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	header := "# Generated by `jacuik export k8s --helm`. The env of each service is set\n# when the chart is installed, like --set-string services.<service>.envValues.<NAME>=value.\n"
+	files["values.yaml"] = append([]byte(header), contents...)
+
+	for _, environment := range options.Environments {
+		environmentValues := map[string]interface{}{
+			"image": map[string]string{"tag": options.Tag},
+			"autoscaling": map[string]int{
+				"minReplicas": defaultMinReplicas,
+				"maxReplicas": defaultMaxReplicas,
+			},
+		}
+		if productionEnvironments[environment] {
+			environmentValues["autoscaling"] = map[string]int{
+				"minReplicas": 2,
+				"maxReplicas": 10,
+			}
+		}
+
+		contents, err := yaml.Marshal(environmentValues)
+		if err != nil {
+			return nil, err
+		}
+
+		header := fmt.Sprintf("# Values for the %s environment, use them on top of values.yaml:\n#\n#     helm upgrade --install %s . -f values-%s.yaml\n", environment, name, environment)
+		files[fmt.Sprintf("values-%s.yaml", environment)] = append([]byte(header), contents...)
+	}
+
+	return files, nil
+}
+
+const helmChartFile = `apiVersion: v2
+name: %s
+description: %q
+type: application
+version: 0.1.0
+appVersion: "1.0.0"
+`
+
+const helmHelpersTemplate = `{{/* The labels of the objects of a service, the service name is passed in. */}}
+{{- define "chart.labels" -}}
+{{ include "chart.selectorLabels" . }}
+app.kubernetes.io/part-of: {{ .root.Chart.Name }}
+app.kubernetes.io/managed-by: {{ .root.Release.Service }}
+helm.sh/chart: {{ .root.Chart.Name }}-{{ .root.Chart.Version }}
+{{- end }}
+
+{{- define "chart.selectorLabels" -}}
+app.kubernetes.io/name: {{ .name }}
+app.kubernetes.io/instance: {{ .root.Release.Name }}
+{{- end }}
+
+{{- define "chart.image" -}}
+{{- if .service.image -}}
+{{ .service.image }}
+{{- else if .root.Values.image.registry -}}
+{{ trimSuffix "/" .root.Values.image.registry }}/{{ .service.repository }}:{{ .root.Values.image.tag }}
+{{- else -}}
+{{ .service.repository }}:{{ .root.Values.image.tag }}
+{{- end }}
+{{- end }}
+`
+
+const helmConfigMapTemplate = `{{- range $name, $service := .Values.services }}
+{{- if $service.config }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ $name }}
+  labels:
+    {{- include "chart.labels" (dict "root" $ "name" $name) | nindent 4 }}
+data:
+  {{- range $key, $value := $service.config }}
+  {{ $key }}: {{ $value | quote }}
+  {{- end }}
+{{- end }}
+{{- end }}
+`
+
+const helmSecretTemplate = `{{- range $name, $service := .Values.services }}
+{{- if or $service.secrets $service.env }}
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ $name }}
+  labels:
+    {{- include "chart.labels" (dict "root" $ "name" $name) | nindent 4 }}
+type: Opaque
+stringData:
+  {{- range $key, $value := $service.secrets }}
+  {{ $key }}: {{ $value | quote }}
+  {{- end }}
+  {{- range $key := $service.env }}
+  {{ $key }}: {{ required (printf "services.%s.envValues.%s must be set" $name $key) (get (default dict $service.envValues) $key) | quote }}
+  {{- end }}
+{{- end }}
+{{- end }}
+`
+
+const helmDeploymentTemplate = `{{- range $name, $service := .Values.services }}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ $name }}
+  labels:
+    {{- include "chart.labels" (dict "root" $ "name" $name) | nindent 4 }}
+spec:
+  {{- if or $service.standIn (not $.Values.autoscaling.enabled) }}
+  replicas: 1
+  {{- end }}
+  selector:
+    matchLabels:
+      {{- include "chart.selectorLabels" (dict "root" $ "name" $name) | nindent 6 }}
+  template:
+    metadata:
+      labels:
+        {{- include "chart.labels" (dict "root" $ "name" $name) | nindent 8 }}
+    spec:
+      containers:
+        - name: {{ $name }}
+          image: {{ include "chart.image" (dict "root" $ "service" $service) }}
+          {{- if $service.port }}
+          ports:
+            - name: http
+              containerPort: {{ $service.port }}
+          {{- end }}
+          {{- if or $service.config $service.secrets $service.env }}
+          envFrom:
+            {{- if $service.config }}
+            - configMapRef:
+                name: {{ $name }}
+            {{- end }}
+            {{- if or $service.secrets $service.env }}
+            - secretRef:
+                name: {{ $name }}
+            {{- end }}
+          {{- end }}
+          {{- if $service.dataPath }}
+          volumeMounts:
+            - name: data
+              mountPath: {{ $service.dataPath }}
+          {{- end }}
+          resources:
+            {{- toYaml $.Values.resources | nindent 12 }}
+          {{- if $service.port }}
+          readinessProbe:
+            tcpSocket:
+              port: http
+            periodSeconds: 10
+          livenessProbe:
+            tcpSocket:
+              port: http
+            initialDelaySeconds: 15
+            periodSeconds: 10
+          {{- end }}
+      {{- if $service.dataPath }}
+      volumes:
+        - name: data
+          emptyDir: {}
+      {{- end }}
+{{- end }}
+`
+
+const helmServiceTemplate = `{{- range $name, $service := .Values.services }}
+{{- if $service.port }}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ $name }}
+  labels:
+    {{- include "chart.labels" (dict "root" $ "name" $name) | nindent 4 }}
+spec:
+  selector:
+    {{- include "chart.selectorLabels" (dict "root" $ "name" $name) | nindent 4 }}
+  ports:
+    - name: http
+      port: {{ $service.port }}
+      targetPort: http
+{{- end }}
+{{- end }}
+`
+
+const helmIngressTemplate = `{{- range $name, $service := .Values.services }}
+{{- if $service.public }}
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: {{ $name }}
+  labels:
+    {{- include "chart.labels" (dict "root" $ "name" $name) | nindent 4 }}
+spec:
+  {{- if $.Values.ingress.className }}
+  ingressClassName: {{ $.Values.ingress.className }}
+  {{- end }}
+  rules:
+    - {{- if $service.host }}
+      host: {{ $service.host }}
+      {{- end }}
+      http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service:
+                name: {{ $name }}
+                port:
+                  name: http
+{{- end }}
+{{- end }}
+`
+
+const helmHorizontalPodAutoscalerTemplate = `{{- if .Values.autoscaling.enabled }}
+{{- range $name, $service := .Values.services }}
+{{- if not $service.standIn }}
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: {{ $name }}
+  labels:
+    {{- include "chart.labels" (dict "root" $ "name" $name) | nindent 4 }}
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: {{ $name }}
+  minReplicas: {{ $.Values.autoscaling.minReplicas }}
+  maxReplicas: {{ $.Values.autoscaling.maxReplicas }}
+  metrics:
+    - type: Resource
+      resource:
+        name: cpu
+        target:
+          type: Utilization
+          averageUtilization: {{ $.Values.autoscaling.targetCPUUtilizationPercentage }}
+{{- end }}
+{{- end }}
+{{- end }}
+`
+
+const helmNotesTemplate = `{{- range $name, $service := .Values.services }}
+{{- if $service.public }}
+{{ $name }} is routed from {{ $service.host | default "every host" }} through the ingress.
+{{- end }}
+{{- end }}
+`
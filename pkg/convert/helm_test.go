@@ -0,0 +1,228 @@
+package convert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/zchase/jacuik/pkg/internal/testutil"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// renderChart renders the templates of a chart like helm template, with
+// the few Helm and Sprig functions the chart uses. values are set on top of
+// values.yaml.
+func renderChart(t *testing.T, files map[string][]byte, values map[string]interface{}) (map[string][]map[string]interface{}, error) {
+	t.Helper()
+
+	var chartValues map[string]interface{}
+	err := yaml.Unmarshal(files["values.yaml"], &chartValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergeValues(chartValues, values)
+
+	templates := template.New("chart")
+	templates.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			var buffer bytes.Buffer
+			err := templates.ExecuteTemplate(&buffer, name, data)
+			return buffer.String(), err
+		},
+		"dict": func(pairs ...interface{}) map[string]interface{} {
+			result := make(map[string]interface{})
+			for i := 0; i+1 < len(pairs); i += 2 {
+				result[pairs[i].(string)] = pairs[i+1]
+			}
+			return result
+		},
+		"get": func(m map[string]interface{}, key string) interface{} {
+			if value, ok := m[key]; ok {
+				return value
+			}
+			return ""
+		},
+		"default": func(fallback, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return fallback
+			}
+			return value
+		},
+		"required": func(message string, value interface{}) (interface{}, error) {
+			if value == nil || value == "" {
+				return nil, errors.New(message)
+			}
+			return value, nil
+		},
+		"quote": func(value interface{}) string {
+			return strconv.Quote(fmt.Sprint(value))
+		},
+		"nindent": func(spaces int, value string) string {
+			indent := strings.Repeat(" ", spaces)
+			return "\n" + indent + strings.ReplaceAll(value, "\n", "\n"+indent)
+		},
+		"toYaml": func(value interface{}) (string, error) {
+			contents, err := yaml.Marshal(value)
+			return strings.TrimSuffix(string(contents), "\n"), err
+		},
+		"trimSuffix": strings.TrimSuffix,
+	})
+
+	for name, contents := range files {
+		if strings.HasPrefix(name, "templates/") {
+			template.Must(templates.New(name).Parse(string(contents)))
+		}
+	}
+
+	data := map[string]interface{}{
+		"Values":  chartValues,
+		"Chart":   map[string]interface{}{"Name": "demo", "Version": "0.1.0"},
+		"Release": map[string]interface{}{"Name": "demo", "Service": "Helm"},
+	}
+
+	rendered := make(map[string][]map[string]interface{})
+	for name := range files {
+		if !strings.HasPrefix(name, "templates/") || !strings.HasSuffix(name, ".yaml") {
+			continue
+		}
+
+		var buffer bytes.Buffer
+		err := templates.ExecuteTemplate(&buffer, name, data)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := yaml.NewDecoder(&buffer)
+		for {
+			var object map[string]interface{}
+			err := decoder.Decode(&object)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s isn't valid YAML: %s\n%s", name, err, buffer.String())
+			}
+			if object != nil {
+				rendered[name] = append(rendered[name], object)
+			}
+		}
+	}
+
+	return rendered, nil
+}
+
+// mergeValues sets values on top of base, like helm --set does.
+func mergeValues(base, values map[string]interface{}) {
+	for key, value := range values {
+		if nested, ok := value.(map[string]interface{}); ok {
+			if baseNested, ok := base[key].(map[string]interface{}); ok {
+				mergeValues(baseNested, nested)
+				continue
+			}
+		}
+		base[key] = value
+	}
+}
+
+// renderedObject returns the rendered object named name.
+func renderedObject(t *testing.T, objects []map[string]interface{}, name string) map[string]interface{} {
+	t.Helper()
+
+	for _, object := range objects {
+		if object["metadata"].(map[string]interface{})["name"] == name {
+			return object
+		}
+	}
+
+	t.Fatalf("nothing named %s was rendered", name)
+	return nil
+}
+
+func TestToHelmChart(t *testing.T) {
+	config, _ := testutil.LoadConfig(t, kubernetesTestConfig, "api", "web")
+
+	files, err := ToHelmChart(config, KubernetesOptions{Tag: "v1", Environments: []string{"dev", "prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"Chart.yaml", "values.yaml", "values-dev.yaml", "values-prod.yaml", "templates/NOTES.txt"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("the chart has no %s", name)
+		}
+	}
+
+	// The env of the services isn't in the values, only its names.
+	values := string(files["values.yaml"])
+	if strings.Contains(values, "debug") || strings.Contains(values, "${") {
+		t.Errorf("the env of api was written to values.yaml:\n%s", values)
+	}
+
+	// Without the env the chart doesn't render.
+	_, err = renderChart(t, files, nil)
+	if err == nil || !strings.Contains(err.Error(), "services.api.envValues.API_KEY must be set") {
+		t.Fatalf("got error %v, want API_KEY to be required", err)
+	}
+
+	rendered, err := renderChart(t, files, map[string]interface{}{
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{
+				"envValues": map[string]interface{}{"API_KEY": "secret", "LOG_LEVEL": "info"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := renderedObject(t, rendered["templates/secret.yaml"], "api")
+	wantSecrets := map[string]interface{}{
+		"API_KEY":   "secret",
+		"LOG_LEVEL": "info",
+		"DB_URL":    "postgres://postgres:postgres@db:5432/postgres",
+	}
+	if got := secret["stringData"]; !reflect.DeepEqual(got, wantSecrets) {
+		t.Errorf("got secrets %v, want %v", got, wantSecrets)
+	}
+
+	configMap := renderedObject(t, rendered["templates/configmap.yaml"], "api")
+	if got, want := configMap["data"], map[string]interface{}{"PORT": "80", "WEB_URL": "http://web:80"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got config %v, want %v", got, want)
+	}
+
+	// The stand-ins match the manifests of jacuik export k8s.
+	manifests, err := ToKubernetes(config, KubernetesOptions{Tag: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, manifest := range manifests {
+		var want map[string]interface{}
+		for _, object := range manifest.Objects {
+			if object.Kind != "Deployment" {
+				continue
+			}
+
+			contents, err := yaml.Marshal(object.Spec.(deploymentSpec).Template.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal(contents, &want); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		name := strings.TrimSuffix(manifest.Name, ".yaml")
+		deployment := renderedObject(t, rendered["templates/deployment.yaml"], name)
+		got := deployment["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"]
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("the pod of %s differs from the manifest\ngot:  %v\nwant: %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,500 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// KubernetesOptions are the settings of a Kubernetes export that aren't in
+// the config.
+type KubernetesOptions struct {
+	// Registry is prepended to the image of each service, like
+	// 123456789012.dkr.ecr.us-west-2.amazonaws.com.
+	Registry string
+	Tag      string
+	// Domain, if set, routes <service>.<domain> to each public service.
+	// Without it the public service is routed from any host, which is only
+	// possible when there is a single one.
+	Domain string
+	// Environments get a values file each in a Helm chart.
+	Environments []string
+}
+
+// workload is a service or a resource stand-in as it is deployed to
+// Kubernetes.
+type workload struct {
+	Name  string
+	Image string
+	// Port is 0 for workers.
+	Port   int
+	Public bool
+	Host   string
+	// Config is the environment of the container, Secrets the part of it
+	// that holds credentials and Env the env of the service from the
+	// config, which may hold ${env:NAME} expressions.
+	Config  map[string]string
+	Secrets map[string]string
+	Env     map[string]string
+	// StandIn is true for the stand-in of a resource.
+	StandIn bool
+	// DataPath is where a stand-in keeps its data.
+	DataPath string
+}
+
+var invalidKubernetesNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// envExpressionPattern matches the ${env:NAME} expressions left in the env
+// of a service.
+var envExpressionPattern = regexp.MustCompile(`\$\{env:([^}]*)\}`)
+
+// kubernetesName returns name as a valid Kubernetes object name.
+func kubernetesName(parts ...string) string {
+	name := strings.ToLower(strings.Join(parts, "-"))
+	return strings.Trim(invalidKubernetesNameCharacters.ReplaceAllString(name, "-"), "-")
+}
+
+// workloads returns what is deployed for the project, the services first.
+func workloads(config *jacuik_config.AppConfig, options KubernetesOptions) ([]workload, error) {
+	var result []workload
+
+	resourceURLs := make(map[string]bool)
+	for _, resource := range config.Resources {
+		resourceURLs[jacuik_config.URLVariable(resource.Name)] = true
+	}
+
+	// The ingresses of public services routed from any host would all claim
+	// the same paths.
+	var publicServices []string
+	for _, svc := range config.Services {
+		if svc.Public && svc.GetKind() != jacuik_config.ServiceKindWorker {
+			publicServices = append(publicServices, svc.Name)
+		}
+	}
+	if len(publicServices) > 1 && options.Domain == "" {
+		return nil, fmt.Errorf("The public services [%s] can't all be routed from any host, a domain is needed to route each from <service>.<domain>.", strings.Join(publicServices, ", "))
+	}
+
+	for _, svc := range config.Services {
+		environment, err := ServiceEnvironment(config, svc)
+		if err != nil {
+			return nil, err
+		}
+
+		w := workload{
+			Name:    kubernetesName(svc.Name),
+			Image:   fmt.Sprintf("%s:%s", kubernetesName(config.Name, svc.Name), options.Tag),
+			Public:  svc.Public && svc.GetKind() != jacuik_config.ServiceKindWorker,
+			Config:  make(map[string]string),
+			Secrets: make(map[string]string),
+			Env:     make(map[string]string),
+		}
+
+		if options.Registry != "" {
+			w.Image = strings.TrimSuffix(options.Registry, "/") + "/" + w.Image
+		}
+
+		if svc.GetKind() != jacuik_config.ServiceKindWorker {
+			w.Port = svc.GetPort()
+		}
+
+		if w.Public && options.Domain != "" {
+			w.Host = fmt.Sprintf("%s.%s", w.Name, options.Domain)
+		}
+
+		// The URLs of resources hold their credentials, and nothing is
+		// known about the env from the config so it is kept secret too.
+		for name, value := range environment {
+			if _, ok := svc.Env[name]; ok {
+				w.Env[name] = value
+			} else if resourceURLs[name] {
+				w.Secrets[name] = value
+			} else {
+				w.Config[name] = value
+			}
+		}
+
+		result = append(result, w)
+	}
+
+	for _, resource := range config.Resources {
+		standIn, err := StandInFor(resource)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, workload{
+			Name:     kubernetesName(resource.Name),
+			Image:    standIn.Image,
+			Port:     standIn.Port,
+			Config:   make(map[string]string),
+			Secrets:  utils.CopyStringMap(standIn.Environment),
+			Env:      make(map[string]string),
+			StandIn:  true,
+			DataPath: standIn.DataPath,
+		})
+	}
+
+	return result, nil
+}
+
+type objectMeta struct {
+	Name   string            `yaml:"name,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+type kubernetesObject struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   objectMeta        `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+	Spec       interface{}       `yaml:"spec,omitempty"`
+}
+
+type labelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type deploymentSpec struct {
+	Replicas int             `yaml:"replicas"`
+	Selector labelSelector   `yaml:"selector"`
+	Template podTemplateSpec `yaml:"template"`
+}
+
+type podTemplateSpec struct {
+	Metadata objectMeta `yaml:"metadata"`
+	Spec     podSpec    `yaml:"spec"`
+}
+
+type podSpec struct {
+	Containers []container `yaml:"containers"`
+	Volumes    []volume    `yaml:"volumes,omitempty"`
+}
+
+type volume struct {
+	Name     string                `yaml:"name"`
+	EmptyDir *emptyDirVolumeSource `yaml:"emptyDir,omitempty"`
+}
+
+type emptyDirVolumeSource struct{}
+
+type container struct {
+	Name           string               `yaml:"name"`
+	Image          string               `yaml:"image"`
+	Ports          []containerPort      `yaml:"ports,omitempty"`
+	EnvFrom        []envFromSource      `yaml:"envFrom,omitempty"`
+	VolumeMounts   []volumeMount        `yaml:"volumeMounts,omitempty"`
+	Resources      resourceRequirements `yaml:"resources"`
+	ReadinessProbe *probe               `yaml:"readinessProbe,omitempty"`
+	LivenessProbe  *probe               `yaml:"livenessProbe,omitempty"`
+}
+
+type volumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+}
+
+type containerPort struct {
+	Name          string `yaml:"name"`
+	ContainerPort int    `yaml:"containerPort"`
+}
+
+type envFromSource struct {
+	ConfigMapRef *objectReference `yaml:"configMapRef,omitempty"`
+	SecretRef    *objectReference `yaml:"secretRef,omitempty"`
+}
+
+type objectReference struct {
+	Name string `yaml:"name"`
+}
+
+type resourceRequirements struct {
+	Requests map[string]string `yaml:"requests"`
+}
+
+type probe struct {
+	TCPSocket           tcpSocketAction `yaml:"tcpSocket"`
+	InitialDelaySeconds int             `yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int             `yaml:"periodSeconds"`
+}
+
+type tcpSocketAction struct {
+	Port string `yaml:"port"`
+}
+
+type serviceSpec struct {
+	Selector map[string]string `yaml:"selector"`
+	Ports    []servicePort     `yaml:"ports"`
+}
+
+type servicePort struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	TargetPort string `yaml:"targetPort"`
+}
+
+type ingressSpec struct {
+	Rules []ingressRule `yaml:"rules"`
+}
+
+type ingressRule struct {
+	Host string           `yaml:"host,omitempty"`
+	HTTP ingressRuleValue `yaml:"http"`
+}
+
+type ingressRuleValue struct {
+	Paths []ingressPath `yaml:"paths"`
+}
+
+type ingressPath struct {
+	Path     string         `yaml:"path"`
+	PathType string         `yaml:"pathType"`
+	Backend  ingressBackend `yaml:"backend"`
+}
+
+type ingressBackend struct {
+	Service ingressServiceBackend `yaml:"service"`
+}
+
+type ingressServiceBackend struct {
+	Name string             `yaml:"name"`
+	Port serviceBackendPort `yaml:"port"`
+}
+
+type serviceBackendPort struct {
+	Name string `yaml:"name"`
+}
+
+type horizontalPodAutoscalerSpec struct {
+	ScaleTargetRef crossVersionObjectReference `yaml:"scaleTargetRef"`
+	MinReplicas    int                         `yaml:"minReplicas"`
+	MaxReplicas    int                         `yaml:"maxReplicas"`
+	Metrics        []metricSpec                `yaml:"metrics"`
+}
+
+type crossVersionObjectReference struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+type metricSpec struct {
+	Type     string             `yaml:"type"`
+	Resource resourceMetricSpec `yaml:"resource"`
+}
+
+type resourceMetricSpec struct {
+	Name   string       `yaml:"name"`
+	Target metricTarget `yaml:"target"`
+}
+
+type metricTarget struct {
+	Type               string `yaml:"type"`
+	AverageUtilization int    `yaml:"averageUtilization"`
+}
+
+// Defaults of the manifests, a Helm chart has them in its values.
+const (
+	defaultCPURequest       = "100m"
+	defaultMemoryRequest    = "128Mi"
+	defaultMinReplicas      = 1
+	defaultMaxReplicas      = 3
+	defaultCPUUtilization   = 70
+	defaultProbePeriod      = 10
+	defaultLivenessDelay    = 15
+	kubernetesPortName      = "http"
+	kubernetesDataVolume    = "data"
+	kubernetesManagedByName = "jacuik"
+)
+
+// KubernetesFile is a manifest file of the export.
+type KubernetesFile struct {
+	Name    string
+	Objects []kubernetesObject
+}
+
+// Marshal returns the objects of the file as YAML documents.
+func (f KubernetesFile) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	for i, object := range f.Objects {
+		if i > 0 {
+			buffer.WriteString("---\n")
+		}
+
+		contents, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(contents)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ToKubernetes converts the project into Kubernetes manifests, a file for
+// each service and resource stand-in. ${env:NAME} expressions left in the
+// env of a service are written as ${NAME}, for envsubst to fill in when the
+// manifests are applied.
+func ToKubernetes(config *jacuik_config.AppConfig, options KubernetesOptions) ([]KubernetesFile, error) {
+	projectWorkloads, err := workloads(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []KubernetesFile
+	for _, w := range projectWorkloads {
+		files = append(files, KubernetesFile{
+			Name:    w.Name + ".yaml",
+			Objects: workloadObjects(config, w),
+		})
+	}
+
+	return files, nil
+}
+
+// workloadObjects returns the objects deployed for a workload.
+func workloadObjects(config *jacuik_config.AppConfig, w workload) []kubernetesObject {
+	selector := map[string]string{
+		"app.kubernetes.io/name":     w.Name,
+		"app.kubernetes.io/instance": kubernetesName(config.Name),
+	}
+	labels := utils.CopyStringMap(selector)
+	labels["app.kubernetes.io/part-of"] = kubernetesName(config.Name)
+	labels["app.kubernetes.io/managed-by"] = kubernetesManagedByName
+
+	var objects []kubernetesObject
+	var envFrom []envFromSource
+
+	if len(w.Config) > 0 {
+		objects = append(objects, kubernetesObject{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Metadata:   objectMeta{Name: w.Name, Labels: labels},
+			Data:       w.Config,
+		})
+		envFrom = append(envFrom, envFromSource{ConfigMapRef: &objectReference{Name: w.Name}})
+	}
+
+	secrets := utils.CopyStringMap(w.Secrets)
+	for name, value := range w.Env {
+		secrets[name] = envExpressionPattern.ReplaceAllString(value, "$${$1}")
+	}
+
+	if len(secrets) > 0 {
+		objects = append(objects, kubernetesObject{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   objectMeta{Name: w.Name, Labels: labels},
+			Type:       "Opaque",
+			StringData: secrets,
+		})
+		envFrom = append(envFrom, envFromSource{SecretRef: &objectReference{Name: w.Name}})
+	}
+
+	c := container{
+		Name:    w.Name,
+		Image:   w.Image,
+		EnvFrom: envFrom,
+		Resources: resourceRequirements{
+			Requests: map[string]string{"cpu": defaultCPURequest, "memory": defaultMemoryRequest},
+		},
+	}
+
+	if w.Port != 0 {
+		c.Ports = []containerPort{{Name: kubernetesPortName, ContainerPort: w.Port}}
+		c.ReadinessProbe = &probe{
+			TCPSocket:     tcpSocketAction{Port: kubernetesPortName},
+			PeriodSeconds: defaultProbePeriod,
+		}
+		c.LivenessProbe = &probe{
+			TCPSocket:           tcpSocketAction{Port: kubernetesPortName},
+			InitialDelaySeconds: defaultLivenessDelay,
+			PeriodSeconds:       defaultProbePeriod,
+		}
+	}
+
+	// Stand-ins keep their data in a volume, like in the compose export.
+	// It lasts as long as the pod, which is enough to try the project out.
+	var volumes []volume
+	if w.DataPath != "" {
+		c.VolumeMounts = []volumeMount{{Name: kubernetesDataVolume, MountPath: w.DataPath}}
+		volumes = []volume{{Name: kubernetesDataVolume, EmptyDir: &emptyDirVolumeSource{}}}
+	}
+
+	objects = append(objects, kubernetesObject{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   objectMeta{Name: w.Name, Labels: labels},
+		Spec: deploymentSpec{
+			Replicas: defaultMinReplicas,
+			Selector: labelSelector{MatchLabels: selector},
+			Template: podTemplateSpec{
+				Metadata: objectMeta{Labels: labels},
+				Spec:     podSpec{Containers: []container{c}, Volumes: volumes},
+			},
+		},
+	})
+
+	if w.Port != 0 {
+		objects = append(objects, kubernetesObject{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Metadata:   objectMeta{Name: w.Name, Labels: labels},
+			Spec: serviceSpec{
+				Selector: selector,
+				Ports:    []servicePort{{Name: kubernetesPortName, Port: w.Port, TargetPort: kubernetesPortName}},
+			},
+		})
+	}
+
+	if w.Public {
+		objects = append(objects, kubernetesObject{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       "Ingress",
+			Metadata:   objectMeta{Name: w.Name, Labels: labels},
+			Spec: ingressSpec{
+				Rules: []ingressRule{{
+					Host: w.Host,
+					HTTP: ingressRuleValue{Paths: []ingressPath{{
+						Path:     "/",
+						PathType: "Prefix",
+						Backend: ingressBackend{Service: ingressServiceBackend{
+							Name: w.Name,
+							Port: serviceBackendPort{Name: kubernetesPortName},
+						}},
+					}}},
+				}},
+			},
+		})
+	}
+
+	// Stand-ins keep their data in the pod, more replicas wouldn't share it.
+	if !w.StandIn {
+		objects = append(objects, kubernetesObject{
+			APIVersion: "autoscaling/v2",
+			Kind:       "HorizontalPodAutoscaler",
+			Metadata:   objectMeta{Name: w.Name, Labels: labels},
+			Spec: horizontalPodAutoscalerSpec{
+				ScaleTargetRef: crossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: w.Name},
+				MinReplicas:    defaultMinReplicas,
+				MaxReplicas:    defaultMaxReplicas,
+				Metrics: []metricSpec{{
+					Type: "Resource",
+					Resource: resourceMetricSpec{
+						Name:   "cpu",
+						Target: metricTarget{Type: "Utilization", AverageUtilization: defaultCPUUtilization},
+					},
+				}},
+			},
+		})
+	}
+
+	return objects
+}
@@ -0,0 +1,162 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zchase/jacuik/pkg/internal/testutil"
+)
+
+const kubernetesTestConfig = `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+    public: true
+    dependsOn: [db, web]
+    env:
+      API_KEY: $${env:API_KEY}
+      LOG_LEVEL: debug
+  - name: web
+    path: ./web
+resources:
+  - name: db
+    type: postgres
+  - name: jobs
+    type: queue
+`
+
+// findObject returns the object of kind in file.
+func findObject(t *testing.T, file KubernetesFile, kind string) kubernetesObject {
+	t.Helper()
+
+	for _, object := range file.Objects {
+		if object.Kind == kind {
+			return object
+		}
+	}
+
+	t.Fatalf("%s has no %s", file.Name, kind)
+	return kubernetesObject{}
+}
+
+func TestToKubernetes(t *testing.T) {
+	config, _ := testutil.LoadConfig(t, kubernetesTestConfig, "api", "web")
+
+	files, err := ToKubernetes(config, KubernetesOptions{Registry: "registry.example.com/", Tag: "v1", Domain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]KubernetesFile)
+	var names []string
+	for _, file := range files {
+		byName[file.Name] = file
+		names = append(names, file.Name)
+	}
+	if want := []string{"api.yaml", "web.yaml", "db.yaml", "jobs.yaml"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got files %v, want %v", names, want)
+	}
+
+	api := byName["api.yaml"]
+
+	// Only what jacuik generates is in the ConfigMap, the env from the
+	// config is kept secret with the URLs holding credentials.
+	if got, want := findObject(t, api, "ConfigMap").Data, map[string]string{"PORT": "80", "WEB_URL": "http://web:80"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got config %v, want %v", got, want)
+	}
+
+	wantSecrets := map[string]string{
+		"API_KEY":   "${API_KEY}",
+		"LOG_LEVEL": "debug",
+		"DB_URL":    "postgres://postgres:postgres@db:5432/postgres",
+	}
+	if got := findObject(t, api, "Secret").StringData; !reflect.DeepEqual(got, wantSecrets) {
+		t.Errorf("got secrets %v, want %v", got, wantSecrets)
+	}
+
+	deployment := findObject(t, api, "Deployment").Spec.(deploymentSpec)
+	if image := deployment.Template.Spec.Containers[0].Image; image != "registry.example.com/demo-api:v1" {
+		t.Errorf("got image %s", image)
+	}
+
+	if host := findObject(t, api, "Ingress").Spec.(ingressSpec).Rules[0].Host; host != "api.example.com" {
+		t.Errorf("got host %s", host)
+	}
+
+	// Private services get no Ingress.
+	for _, object := range byName["web.yaml"].Objects {
+		if object.Kind == "Ingress" {
+			t.Errorf("web is private but has an Ingress")
+		}
+	}
+
+	// The postgres stand-in keeps its data in a volume and isn't scaled.
+	db := byName["db.yaml"]
+	podSpec := findObject(t, db, "Deployment").Spec.(deploymentSpec).Template.Spec
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].EmptyDir == nil {
+		t.Errorf("got volumes %+v, want an emptyDir", podSpec.Volumes)
+	}
+	if mounts := podSpec.Containers[0].VolumeMounts; len(mounts) != 1 || mounts[0].MountPath != "/var/lib/postgresql/data" {
+		t.Errorf("got volume mounts %+v", mounts)
+	}
+	for _, object := range db.Objects {
+		if object.Kind == "HorizontalPodAutoscaler" {
+			t.Errorf("the db stand-in has a HorizontalPodAutoscaler")
+		}
+	}
+
+	contents, err := db.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(contents), "emptyDir: {}") {
+		t.Errorf("got db manifest without an emptyDir:\n%s", contents)
+	}
+
+	// The queue stand-in keeps nothing.
+	if volumes := findObject(t, byName["jobs.yaml"], "Deployment").Spec.(deploymentSpec).Template.Spec.Volumes; len(volumes) != 0 {
+		t.Errorf("got volumes %+v for the queue", volumes)
+	}
+}
+
+func TestToKubernetesPublicServices(t *testing.T) {
+	config, _ := testutil.LoadConfig(t, `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+    public: true
+  - name: web
+    path: ./web
+    public: true
+`, "api", "web")
+
+	// Both ingresses would route every path of every host.
+	_, err := ToKubernetes(config, KubernetesOptions{Tag: "v1"})
+	want := "The public services [api, web] can't all be routed from any host, a domain is needed to route each from <service>.<domain>."
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+
+	_, err = ToHelmChart(config, KubernetesOptions{Tag: "v1", Environments: []string{"dev"}})
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v from the Helm chart, want %q", err, want)
+	}
+
+	files, err := ToKubernetes(config, KubernetesOptions{Tag: "v1", Domain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hosts []string
+	for _, file := range files {
+		for _, rule := range findObject(t, file, "Ingress").Spec.(ingressSpec).Rules {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	if want := []string{"api.example.com", "web.example.com"}; !reflect.DeepEqual(hosts, want) {
+		t.Errorf("the ingresses route %v, want %v", hosts, want)
+	}
+}
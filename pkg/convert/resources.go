@@ -0,0 +1,95 @@
+package convert
+
+import (
+	"fmt"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+// StandIn is the container that stands in for a resource when the project
+// runs outside the cloud.
+type StandIn struct {
+	Image string
+	Port  int
+	// Environment configures the container.
+	Environment map[string]string
+	// DataPath is where the container keeps its data, empty if it keeps
+	// nothing worth a volume.
+	DataPath string
+	// URL is how services connect to the resource, %[1]s is the host and
+	// %[2]s the name of the resource.
+	URL string
+}
+
+// standIns are the containers used for each type of resource.
+var standIns = map[string]StandIn{
+	jacuik_config.ResourceTypePostgres: {
+		Image: "postgres:14-alpine",
+		Port:  5432,
+		Environment: map[string]string{
+			"POSTGRES_USER":     "postgres",
+			"POSTGRES_PASSWORD": "postgres",
+		},
+		DataPath: "/var/lib/postgresql/data",
+		URL:      "postgres://postgres:postgres@%[1]s:5432/postgres",
+	},
+	jacuik_config.ResourceTypeRedis: {
+		Image:    "redis:7-alpine",
+		Port:     6379,
+		DataPath: "/data",
+		URL:      "redis://%[1]s:6379",
+	},
+	// ElasticMQ speaks the SQS API.
+	jacuik_config.ResourceTypeQueue: {
+		Image: "softwaremill/elasticmq-native:1.3.9",
+		Port:  9324,
+		URL:   "http://%[1]s:9324/queue/%[2]s",
+	},
+}
+
+// StandInFor returns the stand-in for a resource.
+func StandInFor(resource jacuik_config.ResourceConfig) (StandIn, error) {
+	standIn, ok := standIns[resource.Type]
+	if !ok {
+		return standIn, fmt.Errorf("Resource [%s] has an unknown type [%s].", resource.Name, resource.Type)
+	}
+
+	return standIn, nil
+}
+
+// ServiceEnvironment returns the environment of a service: the port it
+// listens on, the URL of every service and resource it depends on and its
+// env from the config, which wins over the others.
+func ServiceEnvironment(config *jacuik_config.AppConfig, svc jacuik_config.ServiceConfig) (map[string]string, error) {
+	environment := map[string]string{
+		"PORT": fmt.Sprint(svc.GetPort()),
+	}
+
+	for _, dependency := range svc.DependsOn {
+		if dependencyService, ok := config.GetService(dependency); ok {
+			if dependencyService.GetKind() != jacuik_config.ServiceKindWorker {
+				environment[jacuik_config.URLVariable(dependency)] = fmt.Sprintf("http://%s:%d", dependency, dependencyService.GetPort())
+			}
+			continue
+		}
+
+		for _, resource := range config.Resources {
+			if resource.Name != dependency {
+				continue
+			}
+
+			standIn, err := StandInFor(resource)
+			if err != nil {
+				return nil, err
+			}
+
+			environment[jacuik_config.URLVariable(dependency)] = fmt.Sprintf(standIn.URL, resource.Name, resource.Name)
+		}
+	}
+
+	for name, value := range svc.Env {
+		environment[name] = value
+	}
+
+	return environment, nil
+}
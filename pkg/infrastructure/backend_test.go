@@ -0,0 +1,97 @@
+package infrastructure
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/zchase/jacuik/pkg/internal/testutil"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+// outputProgram deploys no resources, it only exports the outputs of a
+// service so the state has something in it.
+type outputProgram struct{}
+
+func (outputProgram) Run(ctx *pulumi.Context, name string, config *jacuik_config.AppConfig) error {
+	services := pulumi.Map{}
+	for _, svc := range config.Services {
+		services[svc.Name] = pulumi.Map{
+			"serviceName":  pulumi.String(name + "-" + svc.Name),
+			"desiredCount": pulumi.Int(1),
+		}
+	}
+
+	ctx.Export("services", services)
+	return nil
+}
+
+func (outputProgram) ConfigureStack(ctx context.Context, stack auto.Stack, config *jacuik_config.AppConfig) error {
+	return nil
+}
+
+// TestFileBackend deploys to a local backend with the Pulumi CLI.
+func TestFileBackend(t *testing.T) {
+	if _, err := exec.LookPath("pulumi"); err != nil {
+		t.Skip("the pulumi CLI is not installed")
+	}
+
+	t.Setenv("PULUMI_HOME", t.TempDir())
+	t.Setenv("PULUMI_CONFIG_PASSPHRASE", "test")
+
+	config, projectDirectory := testutil.LoadConfig(t, `version: 2
+name: demo
+backend:
+  url: file://./state
+services:
+  - name: api
+    path: ./api
+`, "api")
+
+	infra := NewInfrastructureHandler(testProject, config, outputProgram{})
+
+	outputs, err := infra.Outputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 0 {
+		t.Fatalf("got outputs %v before the first update", outputs)
+	}
+
+	err = infra.Update(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The state is kept in the project, not in the Pulumi login.
+	if _, err := os.Stat(filepath.Join(projectDirectory, "state", ".pulumi")); err != nil {
+		t.Fatalf("the state wasn't written to the backend: %s", err)
+	}
+
+	statuses, err := infra.ServiceStatuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := statuses["api"]; status.ServiceName != "demo-api" || status.DesiredCount != 1 {
+		t.Errorf("got status %+v for api", status)
+	}
+
+	// A new handler finds the same stack.
+	outputs, err = NewInfrastructureHandler(testProject, config, outputProgram{}).Outputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := outputs["services"]; !ok {
+		t.Errorf("got outputs %v, want services", outputs)
+	}
+
+	err = infra.Destroy(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,480 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/ecs"
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/lb"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	awsxgo "github.com/zchase/pulumi-awsx-go/sdk/go/awsx-go"
+	ec2x "github.com/zchase/pulumi-awsx-go/sdk/go/awsx-go/ec2"
+	ecrx "github.com/zchase/pulumi-awsx-go/sdk/go/awsx-go/ecr"
+	ecsx "github.com/zchase/pulumi-awsx-go/sdk/go/awsx-go/ecs"
+	lbx "github.com/zchase/pulumi-awsx-go/sdk/go/awsx-go/lb"
+)
+
+func init() {
+	RegisterProvider(jacuik_config.ProviderECS, func(name string, config *jacuik_config.AppConfig) Provider {
+		return NewInfrastructureHandler(name, config, ECSProgram{})
+	})
+}
+
+// Infrastructure is the resource graph built for a project.
+type Infrastructure struct {
+	// Vpc, Cluster and LoadBalancer are nil when the project adopts
+	// existing ones through its network config.
+	Vpc          *ec2x.Vpc
+	Cluster      *ecs.Cluster
+	LoadBalancer *lbx.ApplicationLoadBalancer
+	Repository   *ecrx.Repository
+	// Services and Resources are keyed by their name from the config.
+	Services  map[string]*ServiceInfrastructure
+	Resources map[string]*ResourceInfrastructure
+
+	SubnetIDs  pulumi.StringArrayOutput
+	ClusterArn pulumi.StringOutput
+	DNSName    pulumi.StringOutput
+}
+
+// ServiceInfrastructure is the part of the resource graph built for a
+// single service.
+type ServiceInfrastructure struct {
+	Image   *ecrx.Image
+	Service *ecsx.FargateService
+	// URL is empty for services that aren't public.
+	URL pulumi.StringOutput
+}
+
+// loadBalancing is where the services receive traffic from, either a new
+// load balancer or a rule on the listener of an existing one.
+type loadBalancing struct {
+	TargetGroup    lb.TargetGroupInput
+	SecurityGroups pulumi.StringArrayOutput
+	DNSName        pulumi.StringOutput
+}
+
+// BuildInfrastructure builds the resource graph for a project. It only
+// registers resources with ctx so it can run under pulumi.RunWithMocks.
+func BuildInfrastructure(ctx *pulumi.Context, name string, config *jacuik_config.AppConfig) (*Infrastructure, error) {
+	// Service paths are relative to the config file, not to wherever
+	// the CLI was run from.
+	projectDirectory, err := config.ProjectDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	infra := &Infrastructure{
+		Services: make(map[string]*ServiceInfrastructure),
+	}
+	network := config.Network
+
+	// Create a VPC, or look up the existing one.
+	var vpcID pulumi.StringOutput
+	if network.VpcID == "" {
+		vpcName := fmt.Sprintf("%s-vpc", name)
+		infra.Vpc, err = ec2x.NewVpc(ctx, vpcName, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		vpcID = infra.Vpc.VpcId
+		infra.SubnetIDs = infra.Vpc.PublicSubnetIds
+	} else {
+		vpc, err := ec2.LookupVpc(ctx, &ec2.LookupVpcArgs{Id: pulumi.StringRef(network.VpcID)})
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't find the VPC [%s]: %w", network.VpcID, err)
+		}
+
+		subnetIDs := network.SubnetIDs
+		if len(subnetIDs) == 0 {
+			subnets, err := ec2.GetSubnets(ctx, &ec2.GetSubnetsArgs{
+				Filters: []ec2.GetSubnetsFilter{{Name: "vpc-id", Values: []string{vpc.Id}}},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("Couldn't find the subnets of the VPC [%s]: %w", network.VpcID, err)
+			}
+
+			if len(subnets.Ids) == 0 {
+				return nil, fmt.Errorf("The VPC [%s] has no subnets.", network.VpcID)
+			}
+
+			subnetIDs = subnets.Ids
+		}
+
+		vpcID = pulumi.String(vpc.Id).ToStringOutput()
+		infra.SubnetIDs = pulumi.ToStringArray(subnetIDs).ToStringArrayOutput()
+	}
+
+	// Create the cluster, or look up the existing one.
+	if network.Cluster == "" {
+		clusterName := fmt.Sprintf("%s-cluster", name)
+		infra.Cluster, err = ecs.NewCluster(ctx, clusterName, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		infra.ClusterArn = infra.Cluster.Arn
+	} else {
+		cluster, err := ecs.LookupCluster(ctx, &ecs.LookupClusterArgs{ClusterName: network.GetClusterName()})
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't find the ECS cluster [%s]: %w", network.Cluster, err)
+		}
+
+		infra.ClusterArn = pulumi.String(cluster.Arn).ToStringOutput()
+	}
+
+	balancing, err := buildLoadBalancing(ctx, name, config, vpcID, infra)
+	if err != nil {
+		return nil, err
+	}
+	infra.DNSName = balancing.DNSName
+
+	repositoryName := fmt.Sprintf("%s-repository", name)
+	infra.Repository, err = ecrx.NewRepository(ctx, repositoryName, nil)
+	if err != nil {
+		return nil, err
+	}
+	repository := infra.Repository
+
+	err = buildResources(ctx, name, config, vpcID, balancing.SecurityGroups, infra)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, svc := range config.Services {
+		imageName := fmt.Sprintf("%s-%s-image", name, svc.Name)
+		image, err := ecrx.NewImage(ctx, imageName, &ecrx.ImageArgs{
+			RepositoryUrl: repository.Url,
+			Path:          pulumi.String(filepath.Join(projectDirectory, svc.PathToDockerfile)),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Workers don't receive traffic so they don't listen on a port, and
+		// only public services are attached to the load balancer.
+		var defs []ecsx.TaskDefinitionPortMappingInput
+		if svc.GetKind() != jacuik_config.ServiceKindWorker {
+			mapping := ecsx.TaskDefinitionPortMappingArgs{
+				ContainerPort: pulumi.IntPtr(svc.GetPort()),
+			}
+			if svc.Public {
+				mapping.TargetGroup = balancing.TargetGroup
+			}
+			defs = append(defs, mapping)
+		}
+
+		// Services get the URL of the resources they depend on, the env
+		// from the config wins over it.
+		variables := make(map[string]pulumi.StringInput)
+		for _, dependency := range svc.DependsOn {
+			if resource, ok := infra.Resources[dependency]; ok {
+				variables[jacuik_config.URLVariable(dependency)] = resource.URL
+			}
+		}
+		for variable, value := range svc.Env {
+			variables[variable] = pulumi.String(value)
+		}
+
+		secrets, executionRoleArgs, err := serviceSecrets(ctx, name, svc, variables)
+		if err != nil {
+			return nil, err
+		}
+
+		taskRole, err := serviceTaskRole(ctx, name, svc, infra)
+		if err != nil {
+			return nil, err
+		}
+
+		var taskRoleArgs *awsxgo.DefaultRoleWithPolicyArgs
+		if taskRole != nil {
+			taskRoleArgs = &awsxgo.DefaultRoleWithPolicyArgs{RoleArn: taskRole.Arn}
+		}
+
+		cloudSvcName := fmt.Sprintf("%s-%s-svc", name, svc.Name)
+		service, err := ecsx.NewFargateService(ctx, cloudSvcName, &ecsx.FargateServiceArgs{
+			Cluster:      infra.ClusterArn,
+			DesiredCount: pulumi.IntPtr(1),
+			NetworkConfiguration: &ecs.ServiceNetworkConfigurationArgs{
+				Subnets:        infra.SubnetIDs,
+				AssignPublicIp: pulumi.BoolPtr(network.GetAssignPublicIP()),
+				SecurityGroups: balancing.SecurityGroups,
+			},
+			TaskDefinitionArgs: &ecsx.FargateServiceTaskDefinitionArgs{
+				Container: &ecsx.TaskDefinitionContainerDefinitionArgs{
+					Image:        image.ImageUri,
+					Cpu:          pulumi.IntPtr(102),
+					Memory:       pulumi.IntPtr(50),
+					PortMappings: ecsx.TaskDefinitionPortMappingArray(defs),
+					Secrets:      secrets,
+				},
+				TaskRole:      taskRoleArgs,
+				ExecutionRole: executionRoleArgs,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		url := pulumi.String("").ToStringOutput()
+		if svc.Public && svc.GetKind() != jacuik_config.ServiceKindWorker {
+			url = pulumi.Sprintf("http://%s", infra.DNSName)
+		}
+
+		infra.Services[svc.Name] = &ServiceInfrastructure{
+			Image:   image,
+			Service: service,
+			URL:     url,
+		}
+	}
+
+	return infra, nil
+}
+
+// buildLoadBalancing creates a load balancer for the services, or forwards
+// to them from the listener of an existing one.
+func buildLoadBalancing(ctx *pulumi.Context, name string, config *jacuik_config.AppConfig, vpcID pulumi.StringOutput, infra *Infrastructure) (*loadBalancing, error) {
+	network := config.Network
+	if network.LoadBalancerArn == "" {
+		albName := fmt.Sprintf("%s-alb", name)
+		alb, err := lbx.NewApplicationLoadBalancer(ctx, albName, &lbx.ApplicationLoadBalancerArgs{
+			SubnetIds: infra.SubnetIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		infra.LoadBalancer = alb
+
+		return &loadBalancing{
+			TargetGroup: alb.DefaultTargetGroup,
+			SecurityGroups: alb.DefaultSecurityGroup.ApplyT(func(sg *ec2.SecurityGroup) pulumi.StringArrayOutput {
+				result := []pulumi.StringOutput{sg.ID().ToStringOutput()}
+				return pulumi.ToStringArrayOutput(result)
+			}).(pulumi.StringArrayOutput),
+			DNSName: alb.LoadBalancer.DnsName(),
+		}, nil
+	}
+
+	loadBalancer, err := lb.LookupLoadBalancer(ctx, &lb.LookupLoadBalancerArgs{Arn: pulumi.StringRef(network.LoadBalancerArn)})
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't find the load balancer [%s]: %w", network.LoadBalancerArn, err)
+	}
+
+	if loadBalancer.VpcId != network.VpcID {
+		return nil, fmt.Errorf("The load balancer [%s] is in the VPC [%s], not [%s].", network.LoadBalancerArn, loadBalancer.VpcId, network.VpcID)
+	}
+
+	// ECS registers each task with the container port of its service, the
+	// port of the target group is only the default for targets registered
+	// without one.
+	targetGroupName := fmt.Sprintf("%s-tg", name)
+	targetGroup, err := lb.NewTargetGroup(ctx, targetGroupName, &lb.TargetGroupArgs{
+		VpcId:      vpcID,
+		Port:       pulumi.IntPtr(targetGroupPort(config)),
+		Protocol:   pulumi.StringPtr("HTTP"),
+		TargetType: pulumi.StringPtr("ip"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a priority the rule goes after the listener's existing
+	// rules, so it only gets the traffic they don't match.
+	ruleName := fmt.Sprintf("%s-rule", name)
+	_, err = lb.NewListenerRule(ctx, ruleName, &lb.ListenerRuleArgs{
+		ListenerArn: pulumi.String(network.ListenerArn),
+		Actions: lb.ListenerRuleActionArray{
+			lb.ListenerRuleActionArgs{
+				Type:           pulumi.String("forward"),
+				TargetGroupArn: targetGroup.Arn,
+			},
+		},
+		Conditions: lb.ListenerRuleConditionArray{
+			lb.ListenerRuleConditionArgs{
+				PathPattern: lb.ListenerRuleConditionPathPatternArgs{
+					Values: pulumi.ToStringArray([]string{"/*"}),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The services only accept traffic from the load balancer.
+	securityGroupName := fmt.Sprintf("%s-sg", name)
+	securityGroup, err := ec2.NewSecurityGroup(ctx, securityGroupName, &ec2.SecurityGroupArgs{
+		VpcId: vpcID,
+		Ingress: ec2.SecurityGroupIngressArray{
+			ec2.SecurityGroupIngressArgs{
+				Protocol:       pulumi.String("tcp"),
+				FromPort:       pulumi.Int(0),
+				ToPort:         pulumi.Int(65535),
+				SecurityGroups: pulumi.ToStringArray(loadBalancer.SecurityGroups),
+			},
+		},
+		Egress: ec2.SecurityGroupEgressArray{
+			ec2.SecurityGroupEgressArgs{
+				Protocol:   pulumi.String("-1"),
+				FromPort:   pulumi.Int(0),
+				ToPort:     pulumi.Int(0),
+				CidrBlocks: pulumi.ToStringArray([]string{"0.0.0.0/0"}),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &loadBalancing{
+		TargetGroup:    targetGroup,
+		SecurityGroups: pulumi.ToStringArrayOutput([]pulumi.StringOutput{securityGroup.ID().ToStringOutput()}),
+		DNSName:        pulumi.String(loadBalancer.DnsName).ToStringOutput(),
+	}, nil
+}
+
+// ECSProgram deploys the project to AWS ECS on Fargate, behind an
+// application load balancer in a new VPC unless the network config adopts
+// existing ones. Resources are provisioned as RDS Postgres, ElastiCache
+// Redis and SQS queues.
+type ECSProgram struct{}
+
+func (ECSProgram) Run(ctx *pulumi.Context, name string, config *jacuik_config.AppConfig) error {
+	infra, err := BuildInfrastructure(ctx, name, config)
+	if err != nil {
+		return err
+	}
+
+	serviceOutputs := pulumi.Map{}
+	for serviceName, svc := range infra.Services {
+		serviceOutputs[serviceName] = pulumi.Map{
+			"serviceName":  svc.Service.Service.Name(),
+			"imageUri":     svc.Image.ImageUri,
+			"desiredCount": svc.Service.Service.DesiredCount(),
+			"url":          svc.URL,
+		}
+	}
+
+	ctx.Export("serviceUrl", infra.DNSName)
+	ctx.Export("clusterArn", infra.ClusterArn)
+	ctx.Export("services", serviceOutputs)
+	return nil
+}
+
+func (ECSProgram) ConfigureStack(ctx context.Context, stack auto.Stack, config *jacuik_config.AppConfig) error {
+	// Plugins
+	err := stack.Workspace().InstallPlugin(ctx, "aws", "v5.6.0")
+	if err != nil {
+		return err
+	}
+
+	// TODO: enable this when awsx-go is available.
+	// err = workspace.InstallPlugin(ctx, "awsx-go", "v0.0.1")
+	// if err != nil {
+	// 	return err
+	// }
+
+	err = ensureDatabasePassword(ctx, stack, config)
+	if err != nil {
+		return err
+	}
+
+	// TODO: make this configurable
+	return stack.SetConfig(ctx, "aws:region", auto.ConfigValue{Value: "us-west-2"})
+}
+
+type describeServicesResult struct {
+	Services []struct {
+		ServiceName  string `json:"serviceName"`
+		RunningCount int    `json:"runningCount"`
+	} `json:"services"`
+}
+
+// RunningCounts asks ECS, through the aws CLI, how many tasks are running
+// for each service.
+func (ECSProgram) RunningCounts(outputs auto.OutputMap, serviceNames []string) (map[string]int, error) {
+	var clusterArn string
+	if clusterOutput, ok := outputs["clusterArn"]; ok {
+		clusterArn, _ = clusterOutput.Value.(string)
+	}
+
+	if clusterArn == "" || len(serviceNames) == 0 {
+		return nil, fmt.Errorf("No deployed services to describe.")
+	}
+
+	// arn:aws:ecs:<region>:<account>:cluster/<name>
+	arnParts := strings.Split(clusterArn, ":")
+	if len(arnParts) < 4 {
+		return nil, fmt.Errorf("Invalid cluster ARN [%s].", clusterArn)
+	}
+
+	result := make(map[string]int)
+
+	// ECS only describes up to 10 services per request.
+	for start := 0; start < len(serviceNames); start += 10 {
+		end := start + 10
+		if end > len(serviceNames) {
+			end = len(serviceNames)
+		}
+
+		args := []string{"ecs", "describe-services", "--output", "json", "--region", arnParts[3], "--cluster", clusterArn, "--services"}
+		args = append(args, serviceNames[start:end]...)
+
+		output, err := exec.Command("aws", args...).Output()
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("The aws CLI is needed to count the running tasks.")
+		}
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("Couldn't describe the ECS services [%s].", strings.TrimSpace(string(exitError.Stderr)))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var described describeServicesResult
+		err = json.Unmarshal(output, &described)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, svc := range described.Services {
+			result[svc.ServiceName] = svc.RunningCount
+		}
+	}
+
+	return result, nil
+}
+
+// sortedKeys returns the keys of m in order so the task definition doesn't
+// change from one run to the next.
+// targetGroupPort returns the port of the first public service, the
+// default service port when there is none.
+func targetGroupPort(config *jacuik_config.AppConfig) int {
+	for _, svc := range config.Services {
+		if svc.Public && svc.GetKind() != jacuik_config.ServiceKindWorker {
+			return svc.GetPort()
+		}
+	}
+
+	return jacuik_config.DefaultServicePort
+}
+
+func sortedKeys(m map[string]pulumi.StringInput) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
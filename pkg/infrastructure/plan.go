@@ -0,0 +1,341 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+// planFileVersion is the version of the plan file format.
+const planFileVersion = 1
+
+// PlanFile is a saved preview. It holds the Pulumi update plan together with
+// what the plan was made from, so an update can refuse to run when the
+// project or the stack has changed since.
+type PlanFile struct {
+	Version   int    `json:"version"`
+	Project   string `json:"project"`
+	Stack     string `json:"stack"`
+	CreatedAt string `json:"createdAt"`
+	// StackVersion is the version of the last update of the stack.
+	StackVersion int `json:"stackVersion"`
+	// Fingerprint is a hash of Sources.
+	Fingerprint string `json:"fingerprint"`
+	// Sources are the hashes of the config files and of the files in the
+	// service directories, by path relative to the project directory.
+	Sources map[string]string `json:"sources"`
+	Summary *PreviewSummary   `json:"summary"`
+	Plan    json.RawMessage   `json:"plan"`
+
+	// path is where the plan was read from.
+	path string
+}
+
+// ReadPlanFile reads a plan saved by PreviewPlan.
+func ReadPlanFile(filePath string) (*PlanFile, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan PlanFile
+	err = json.Unmarshal(contents, &plan)
+	if err != nil {
+		return nil, fmt.Errorf("The plan file [%s] is not valid: %s.", filePath, err)
+	}
+
+	if plan.Version != planFileVersion || len(plan.Plan) == 0 || plan.Summary == nil {
+		return nil, fmt.Errorf("The plan file [%s] was not made by `jacuik preview --save-plan`.", filePath)
+	}
+
+	plan.path = filePath
+	return &plan, nil
+}
+
+// PreviewPlan previews an update like Preview and saves the plan to
+// planPath.
+func (i *InfrastructureHandler) PreviewPlan(progressWriter io.Writer, planPath string) (*PreviewSummary, error) {
+	sources, err := i.sourceHashes(planPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, stack, err := i.configureApplicationStack()
+	if err != nil {
+		return nil, err
+	}
+
+	stackVersion, err := lastUpdateVersion(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	enableUpdatePlans(stack)
+
+	pulumiPlan, err := os.CreateTemp("", "jacuik-plan-*.json")
+	if err != nil {
+		return nil, err
+	}
+	pulumiPlan.Close()
+	defer os.Remove(pulumiPlan.Name())
+
+	summary, err := previewStack(ctx, stack, progressWriter, optpreview.Plan(pulumiPlan.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(pulumiPlan.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	plan := PlanFile{
+		Version:      planFileVersion,
+		Project:      i.Name,
+		Stack:        stack.Name(),
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		StackVersion: stackVersion,
+		Fingerprint:  fingerprint(sources),
+		Sources:      sources,
+		Summary:      summary,
+		Plan:         contents,
+	}
+
+	contents, err = json.MarshalIndent(plan, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(planPath, append(contents, '\n'), 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// CheckPlan returns an error when the project or the stack changed since
+// the plan was made.
+func (i *InfrastructureHandler) CheckPlan(plan *PlanFile) error {
+	if plan.Project != i.Name || plan.Stack != i.stackName() {
+		return fmt.Errorf("The plan was made for stack [%s/%s], not [%s/%s].", plan.Project, plan.Stack, i.Name, i.stackName())
+	}
+
+	sources, err := i.sourceHashes(plan.path)
+	if err != nil {
+		return err
+	}
+
+	if fingerprint(sources) != plan.Fingerprint {
+		return fmt.Errorf("The project changed since the plan was made:\n\n    %s\n\nRun `jacuik preview --save-plan` again.", strings.Join(changedSources(plan.Sources, sources), "\n    "))
+	}
+
+	ctx, stack, err := i.configureApplicationStack()
+	if err != nil {
+		return err
+	}
+
+	stackVersion, err := lastUpdateVersion(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	if stackVersion != plan.StackVersion {
+		return fmt.Errorf("The stack was updated since the plan was made (version %d, the plan was made at version %d). Run `jacuik preview --save-plan` again.", stackVersion, plan.StackVersion)
+	}
+
+	return nil
+}
+
+// UpdatePlan updates the stack like Update, making only the changes in the
+// plan. Pulumi fails the update if it would do anything else.
+func (i *InfrastructureHandler) UpdatePlan(progressWriter io.Writer, plan *PlanFile) error {
+	ctx, stack, err := i.configureApplicationStack()
+	if err != nil {
+		return err
+	}
+
+	enableUpdatePlans(stack)
+
+	pulumiPlan, err := os.CreateTemp("", "jacuik-plan-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(pulumiPlan.Name())
+
+	_, err = pulumiPlan.Write(plan.Plan)
+	pulumiPlan.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = stack.Up(ctx, optup.ProgressStreams(progressWriter), optup.Plan(pulumiPlan.Name()))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// enableUpdatePlans turns on Pulumi's update plans, they are still an
+// experimental feature of the Pulumi CLI.
+func enableUpdatePlans(stack auto.Stack) {
+	stack.Workspace().SetEnvVar("PULUMI_EXPERIMENTAL", "true")
+}
+
+// lastUpdateVersion returns the version of the last update of the stack,
+// 0 if it was never updated. Previews aren't recorded so they don't count.
+func lastUpdateVersion(ctx context.Context, stack auto.Stack) (int, error) {
+	history, err := stack.History(ctx, 1, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(history) == 0 {
+		return 0, nil
+	}
+
+	return history[0].Version, nil
+}
+
+// sourceHashes hashes the config files and the files in each service's
+// build context, the inputs of the deployment besides the stack itself.
+// Files the .dockerignore of a build context leaves out, jacuik's own files
+// and the plan at planPath aren't inputs, so they don't invalidate a plan.
+func (i *InfrastructureHandler) sourceHashes(planPath string) (map[string]string, error) {
+	projectDirectory, err := i.Config.ProjectDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	if planPath != "" {
+		planPath, err = filepath.Abs(planPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sources := make(map[string]string)
+	addFile := func(filePath string) error {
+		contents, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(projectDirectory, filePath)
+		if err != nil {
+			relativePath = filePath
+		}
+
+		hash := sha256.Sum256(contents)
+		sources[filepath.ToSlash(relativePath)] = hex.EncodeToString(hash[:])
+		return nil
+	}
+
+	for _, configFile := range i.Config.ConfigFiles() {
+		err = addFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, svc := range i.Config.Services {
+		serviceDirectory, err := filepath.Abs(filepath.Join(projectDirectory, svc.PathToDockerfile))
+		if err != nil {
+			return nil, err
+		}
+
+		dockerIgnore, err := utils.LoadDockerIgnore(serviceDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the .dockerignore of service [%s]: %s", svc.Name, err)
+		}
+
+		err = filepath.WalkDir(serviceDirectory, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			relativePath, err := filepath.Rel(serviceDirectory, filePath)
+			if err != nil {
+				return err
+			}
+			relativePath = filepath.ToSlash(relativePath)
+
+			if entry.IsDir() {
+				if entry.Name() == ".git" || entry.Name() == ".jacuik" {
+					return filepath.SkipDir
+				}
+				if relativePath != "." && dockerIgnore.SkipDirectory(relativePath) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !entry.Type().IsRegular() || filePath == planPath || dockerIgnore.Ignored(relativePath) {
+				return nil
+			}
+
+			return addFile(filePath)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the files of service [%s]: %s", svc.Name, err)
+		}
+	}
+
+	return sources, nil
+}
+
+func fingerprint(sources map[string]string) string {
+	var paths []string
+	for path := range sources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(hash, "%s %s\n", sources[path], path)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// changedSources lists the files that were added, removed or changed
+// between two sets of source hashes.
+func changedSources(before, after map[string]string) []string {
+	var changes []string
+	for path, hash := range after {
+		previous, ok := before[path]
+		switch {
+		case !ok:
+			changes = append(changes, "added:   "+path)
+		case previous != hash:
+			changes = append(changes, "changed: "+path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, "removed: "+path)
+		}
+	}
+
+	sort.Slice(changes, func(x, y int) bool {
+		return changes[x][9:] < changes[y][9:]
+	})
+
+	return changes
+}
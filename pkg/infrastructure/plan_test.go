@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/zchase/jacuik/pkg/internal/testutil"
+)
+
+func TestSourceHashesPlanInProject(t *testing.T) {
+	config, projectDirectory := testutil.LoadConfig(t, `version: 2
+name: demo
+services:
+  - name: api
+    path: .
+    public: true
+`, ".")
+
+	files := map[string]string{
+		".dockerignore":              "node_modules\n*.log\n!keep.log\n",
+		"main.go":                    "package main\n",
+		"debug.log":                  "ignored\n",
+		"keep.log":                   "kept\n",
+		"node_modules/left-pad.js":   "ignored\n",
+		".jacuik/jacuik.schema.json": "{}\n",
+		"plans/prod.json":            "{}\n",
+	}
+	for name, contents := range files {
+		filePath := filepath.Join(projectDirectory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infra := NewInfrastructureHandler(testProject, config, ECSProgram{})
+	planPath := filepath.Join(projectDirectory, "plans", "prod.json")
+
+	before, err := infra.sourceHashes(planPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for path := range before {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	want := []string{".dockerignore", "Dockerfile", "keep.log", "main.go", "schema.yaml"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("got sources %v, want %v", paths, want)
+	}
+
+	// Saving the plan, or changing ignored files, doesn't change the
+	// project.
+	for _, name := range []string{"plans/prod.json", "debug.log", "node_modules/left-pad.js", ".jacuik/jacuik.schema.json"} {
+		err = os.WriteFile(filepath.Join(projectDirectory, filepath.FromSlash(name)), []byte("changed\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	after, err := infra.sourceHashes(planPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint(after) != fingerprint(before) {
+		t.Errorf("the fingerprint changed, changed sources: %v", changedSources(before, after))
+	}
+
+	err = os.WriteFile(filepath.Join(projectDirectory, "keep.log"), []byte("changed\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err = infra.sourceHashes(planPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := changedSources(before, after); len(changes) != 1 {
+		t.Errorf("got changed sources %v, want keep.log", changes)
+	}
+}
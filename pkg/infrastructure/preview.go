@@ -0,0 +1,258 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// The operations a preview can plan for a resource, in the order they are
+// summarized.
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationReplace = "replace"
+	OperationDelete  = "delete"
+)
+
+var summaryOperations = []string{OperationCreate, OperationUpdate, OperationReplace, OperationDelete}
+
+// propertyPathSegment matches one segment of a property path like
+// containerDefinitions[0].image.
+var propertyPathSegment = regexp.MustCompile(`[^.\[\]]+|\[\d+\]`)
+
+// PropertyChange is a change to a single property of a resource.
+type PropertyChange struct {
+	Path string `json:"path"`
+	// Kind is add, delete or update.
+	Kind string `json:"kind"`
+	// Replaces is true when the change forces the resource to be replaced.
+	Replaces bool        `json:"replaces,omitempty"`
+	Old      interface{} `json:"old,omitempty"`
+	New      interface{} `json:"new,omitempty"`
+}
+
+// ResourceChange is a change a preview plans to make to a resource.
+type ResourceChange struct {
+	URN       string `json:"urn"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Operation string `json:"operation"`
+	// ReplaceReasons are the properties that force a replacement.
+	ReplaceReasons []string         `json:"replaceReasons,omitempty"`
+	Properties     []PropertyChange `json:"properties,omitempty"`
+}
+
+// PreviewSummary is the result of a preview.
+type PreviewSummary struct {
+	Changes []ResourceChange `json:"changes"`
+	// Counts is the number of resources per operation.
+	Counts map[string]int `json:"counts"`
+}
+
+// Text returns a one line summary like "2 to create, 1 to update".
+func (p *PreviewSummary) Text() string {
+	var parts []string
+	for _, operation := range summaryOperations {
+		if count := p.Counts[operation]; count > 0 {
+			parts = append(parts, fmt.Sprintf("%d to %s", count, operation))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "no changes"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Preview previews an update of the stack and returns the changes it would
+// make. Progress is written to progressWriter as the preview runs.
+func (i *InfrastructureHandler) Preview(progressWriter io.Writer) (*PreviewSummary, error) {
+	ctx, stack, err := i.configureApplicationStack()
+	if err != nil {
+		return nil, err
+	}
+
+	return previewStack(ctx, stack, progressWriter)
+}
+
+func previewStack(ctx context.Context, stack auto.Stack, progressWriter io.Writer, opts ...optpreview.Option) (*PreviewSummary, error) {
+	engineEvents := make(chan events.EngineEvent)
+	// Buffered so the collector can finish when the preview fails and the
+	// changes are never read.
+	collected := make(chan []ResourceChange, 1)
+	go func() {
+		collected <- collectResourceChanges(engineEvents)
+	}()
+
+	opts = append(opts, optpreview.ProgressStreams(progressWriter), optpreview.EventStreams(engineEvents))
+	_, err := stack.Preview(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &PreviewSummary{
+		Changes: <-collected,
+		Counts:  make(map[string]int),
+	}
+	for _, change := range summary.Changes {
+		summary.Counts[change.Operation]++
+	}
+
+	return summary, nil
+}
+
+// collectResourceChanges reads engine events until the channel is closed
+// and returns the resources that would change.
+func collectResourceChanges(engineEvents <-chan events.EngineEvent) []ResourceChange {
+	var changes []ResourceChange
+	for event := range engineEvents {
+		if event.ResourcePreEvent == nil {
+			continue
+		}
+
+		change, ok := resourceChange(event.ResourcePreEvent.Metadata)
+		if ok {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+func resourceChange(metadata apitype.StepEventMetadata) (ResourceChange, bool) {
+	// A replacement also shows up as create-replacement and delete-replaced
+	// steps, only the replace step is kept.
+	operation := ""
+	switch metadata.Op {
+	case apitype.OpCreate:
+		operation = OperationCreate
+	case apitype.OpUpdate:
+		operation = OperationUpdate
+	case apitype.OpReplace:
+		operation = OperationReplace
+	case apitype.OpDelete:
+		operation = OperationDelete
+	default:
+		return ResourceChange{}, false
+	}
+
+	// The stack itself is left out like in the resource view.
+	if metadata.Type == "pulumi:pulumi:Stack" {
+		return ResourceChange{}, false
+	}
+
+	change := ResourceChange{
+		URN:            metadata.URN,
+		Type:           metadata.Type,
+		Name:           metadata.URN[strings.LastIndex(metadata.URN, "::")+2:],
+		Operation:      operation,
+		ReplaceReasons: metadata.Keys,
+	}
+
+	var oldInputs, newInputs map[string]interface{}
+	if metadata.Old != nil {
+		oldInputs = metadata.Old.Inputs
+	}
+	if metadata.New != nil {
+		newInputs = metadata.New.Inputs
+	}
+
+	if len(metadata.DetailedDiff) > 0 {
+		for path, diff := range metadata.DetailedDiff {
+			kind := strings.TrimSuffix(string(diff.Kind), "-replace")
+			change.Properties = append(change.Properties, PropertyChange{
+				Path:     path,
+				Kind:     kind,
+				Replaces: strings.HasSuffix(string(diff.Kind), "-replace"),
+				Old:      propertyValue(oldInputs, path),
+				New:      propertyValue(newInputs, path),
+			})
+		}
+	} else {
+		// Providers without detailed diffs only report the keys that changed.
+		for _, path := range metadata.Diffs {
+			change.Properties = append(change.Properties, PropertyChange{
+				Path:     path,
+				Kind:     string(apitype.DiffUpdate),
+				Replaces: containsString(metadata.Keys, path),
+				Old:      propertyValue(oldInputs, path),
+				New:      propertyValue(newInputs, path),
+			})
+		}
+	}
+
+	sort.Slice(change.Properties, func(x, y int) bool {
+		return change.Properties[x].Path < change.Properties[y].Path
+	})
+
+	return change, true
+}
+
+// propertyValue looks up a property path like containerDefinitions[0].image
+// in a resource's inputs.
+func propertyValue(inputs map[string]interface{}, path string) interface{} {
+	var value interface{} = inputs
+	for _, segment := range propertyPathSegment.FindAllString(path, -1) {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			value = current[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(strings.Trim(segment, "[]"))
+			if err != nil || index < 0 || index >= len(current) {
+				return nil
+			}
+			value = current[index]
+		default:
+			return nil
+		}
+	}
+
+	return value
+}
+
+// FormatPropertyValue formats a property value on one line.
+func FormatPropertyValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+
+	var text string
+	if s, ok := value.(string); ok {
+		text = strconv.Quote(s)
+	} else {
+		contents, err := json.Marshal(value)
+		if err != nil {
+			text = fmt.Sprint(value)
+		} else {
+			text = string(contents)
+		}
+	}
+
+	if len(text) > 80 {
+		text = text[:77] + "..."
+	}
+
+	return text
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
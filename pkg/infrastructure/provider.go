@@ -0,0 +1,98 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+// Provider deploys a project to a cloud. The provider a project uses is set
+// with provider in the config.
+type Provider interface {
+	// BuildGraph registers the resources of the project with ctx.
+	BuildGraph(ctx *pulumi.Context) error
+	// Preview returns the changes an update would make.
+	Preview(progressWriter io.Writer) (*PreviewSummary, error)
+	Update(progressWriter io.Writer) error
+	Destroy(progressWriter io.Writer) error
+	// Outputs returns the outputs of the deployed project, an empty map if
+	// it was never deployed.
+	Outputs() (auto.OutputMap, error)
+}
+
+// PlanProvider is a Provider that can save a preview as a plan and apply
+// exactly that plan later.
+type PlanProvider interface {
+	Provider
+	PreviewPlan(progressWriter io.Writer, planPath string) (*PreviewSummary, error)
+	CheckPlan(plan *PlanFile) error
+	UpdatePlan(progressWriter io.Writer, plan *PlanFile) error
+}
+
+// SecretsProvider is a Provider whose secrets encryption can be changed.
+type SecretsProvider interface {
+	Provider
+	RotateSecretsProvider(progressWriter io.Writer) error
+}
+
+// ServiceStatusProvider is a Provider that reports the deployed state of
+// the services.
+type ServiceStatusProvider interface {
+	Provider
+	ServiceStatuses() (map[string]ServiceStatus, error)
+}
+
+// ProviderFactory creates a provider for the project name with config.
+type ProviderFactory func(name string, config *jacuik_config.AppConfig) Provider
+
+var providers = make(map[string]ProviderFactory)
+
+// RegisterProvider makes a provider available under name, projects select it
+// with provider: name in the config.
+func RegisterProvider(name string, factory ProviderFactory) {
+	providers[name] = factory
+	jacuik_config.RegisterProviderName(name)
+}
+
+// NewProvider returns the provider the config selects.
+func NewProvider(name string, config *jacuik_config.AppConfig) (Provider, error) {
+	providerName := config.GetProvider()
+
+	factory, ok := providers[providerName]
+	if !ok {
+		var names []string
+		for registered := range providers {
+			names = append(names, registered)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("Unknown provider [%s], expected one of [%s].", providerName, strings.Join(names, ", "))
+	}
+
+	return factory(name, config), nil
+}
+
+// PulumiProgram is the part of a provider that differs between clouds when
+// the project is deployed with Pulumi. InfrastructureHandler does the rest.
+type PulumiProgram interface {
+	// Run registers the resources of the project with ctx and exports its
+	// outputs.
+	Run(ctx *pulumi.Context, name string, config *jacuik_config.AppConfig) error
+	// ConfigureStack installs the plugins and sets the config the program
+	// needs to deploy config.
+	ConfigureStack(ctx context.Context, stack auto.Stack, config *jacuik_config.AppConfig) error
+}
+
+// runningCounter is a PulumiProgram that can tell how many instances of each
+// service are running.
+type runningCounter interface {
+	// RunningCounts returns the running count by the serviceName output of
+	// each service.
+	RunningCounts(outputs auto.OutputMap, serviceNames []string) (map[string]int, error)
+}
@@ -0,0 +1,375 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/elasticache"
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/rds"
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi-aws/sdk/v5/go/aws/sqs"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	pulumiconfig "github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	awsxgo "github.com/zchase/pulumi-awsx-go/sdk/go/awsx-go"
+	ecsx "github.com/zchase/pulumi-awsx-go/sdk/go/awsx-go/ecs"
+)
+
+// databasePasswordKey is the stack config holding the password of the
+// Postgres resources. It is generated the first time the stack is
+// configured.
+const databasePasswordKey = "jacuik:databasePassword"
+
+// databaseUser is the user the services connect to Postgres resources as.
+const databaseUser = "jacuik"
+
+// taskExecutionPolicyArn lets ECS pull the images of the services and write
+// their logs.
+const taskExecutionPolicyArn = "arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"
+
+// ResourceInfrastructure is the part of the resource graph built for a
+// resource from the config.
+type ResourceInfrastructure struct {
+	Type     string
+	Resource pulumi.Resource
+	// URL is how services connect to the resource, they get it in
+	// <NAME>_URL.
+	URL pulumi.StringOutput
+	// Arn is set for queues, services depending on them are allowed to use
+	// them.
+	Arn pulumi.StringOutput
+}
+
+// buildResources provisions the resources of the project in its subnets.
+// Postgres and Redis only accept connections from serviceSecurityGroups.
+func buildResources(ctx *pulumi.Context, name string, config *jacuik_config.AppConfig, vpcID pulumi.StringOutput, serviceSecurityGroups pulumi.StringArrayOutput, infra *Infrastructure) error {
+	infra.Resources = make(map[string]*ResourceInfrastructure)
+	if len(config.Resources) == 0 {
+		return nil
+	}
+
+	// The network resources are shared by every resource that needs them
+	// and only created once.
+	var securityGroup *ec2.SecurityGroup
+	resourceSecurityGroups := func() (pulumi.StringArrayOutput, error) {
+		if securityGroup == nil {
+			var err error
+			securityGroup, err = ec2.NewSecurityGroup(ctx, fmt.Sprintf("%s-resources-sg", name), &ec2.SecurityGroupArgs{
+				VpcId: vpcID,
+				Ingress: ec2.SecurityGroupIngressArray{
+					ec2.SecurityGroupIngressArgs{
+						Protocol:       pulumi.String("tcp"),
+						FromPort:       pulumi.Int(5432),
+						ToPort:         pulumi.Int(5432),
+						SecurityGroups: serviceSecurityGroups,
+					},
+					ec2.SecurityGroupIngressArgs{
+						Protocol:       pulumi.String("tcp"),
+						FromPort:       pulumi.Int(6379),
+						ToPort:         pulumi.Int(6379),
+						SecurityGroups: serviceSecurityGroups,
+					},
+				},
+			})
+			if err != nil {
+				return pulumi.StringArrayOutput{}, err
+			}
+		}
+
+		return pulumi.ToStringArrayOutput([]pulumi.StringOutput{securityGroup.ID().ToStringOutput()}), nil
+	}
+
+	var databaseSubnetGroup *rds.SubnetGroup
+	var cacheSubnetGroup *elasticache.SubnetGroup
+
+	for _, resource := range config.Resources {
+		resourceName := fmt.Sprintf("%s-%s", name, resource.Name)
+
+		switch resource.Type {
+		case jacuik_config.ResourceTypePostgres:
+			if pulumiconfig.Get(ctx, databasePasswordKey) == "" {
+				return fmt.Errorf("The stack has no database password [%s] for the resource [%s].", databasePasswordKey, resource.Name)
+			}
+			password := pulumiconfig.GetSecret(ctx, databasePasswordKey)
+
+			securityGroups, err := resourceSecurityGroups()
+			if err != nil {
+				return err
+			}
+
+			if databaseSubnetGroup == nil {
+				databaseSubnetGroup, err = rds.NewSubnetGroup(ctx, fmt.Sprintf("%s-db-subnets", name), &rds.SubnetGroupArgs{
+					SubnetIds: infra.SubnetIDs,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			databaseName := databaseName(resource.Name)
+			instance, err := rds.NewInstance(ctx, fmt.Sprintf("%s-db", resourceName), &rds.InstanceArgs{
+				Engine:              pulumi.String("postgres"),
+				EngineVersion:       pulumi.String("14"),
+				InstanceClass:       pulumi.String("db.t3.micro"),
+				AllocatedStorage:    pulumi.Int(20),
+				DbName:              pulumi.String(databaseName),
+				Username:            pulumi.String(databaseUser),
+				Password:            password,
+				DbSubnetGroupName:   databaseSubnetGroup.Name,
+				VpcSecurityGroupIds: securityGroups,
+				SkipFinalSnapshot:   pulumi.Bool(true),
+			})
+			if err != nil {
+				return err
+			}
+
+			infra.Resources[resource.Name] = &ResourceInfrastructure{
+				Type:     resource.Type,
+				Resource: instance,
+				URL:      pulumi.Sprintf("postgres://%s:%s@%s/%s", databaseUser, password, instance.Endpoint, databaseName),
+			}
+		case jacuik_config.ResourceTypeRedis:
+			securityGroups, err := resourceSecurityGroups()
+			if err != nil {
+				return err
+			}
+
+			if cacheSubnetGroup == nil {
+				cacheSubnetGroup, err = elasticache.NewSubnetGroup(ctx, fmt.Sprintf("%s-cache-subnets", name), &elasticache.SubnetGroupArgs{
+					SubnetIds: infra.SubnetIDs,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			cluster, err := elasticache.NewCluster(ctx, fmt.Sprintf("%s-cache", resourceName), &elasticache.ClusterArgs{
+				Engine:           pulumi.String("redis"),
+				NodeType:         pulumi.String("cache.t3.micro"),
+				NumCacheNodes:    pulumi.Int(1),
+				Port:             pulumi.Int(6379),
+				SubnetGroupName:  cacheSubnetGroup.Name,
+				SecurityGroupIds: securityGroups,
+			})
+			if err != nil {
+				return err
+			}
+
+			infra.Resources[resource.Name] = &ResourceInfrastructure{
+				Type:     resource.Type,
+				Resource: cluster,
+				URL:      pulumi.Sprintf("redis://%s:6379", cluster.CacheNodes.Index(pulumi.Int(0)).Address().Elem()),
+			}
+		case jacuik_config.ResourceTypeQueue:
+			queue, err := sqs.NewQueue(ctx, fmt.Sprintf("%s-queue", resourceName), nil)
+			if err != nil {
+				return err
+			}
+
+			infra.Resources[resource.Name] = &ResourceInfrastructure{
+				Type:     resource.Type,
+				Resource: queue,
+				URL:      queue.Url,
+				Arn:      queue.Arn,
+			}
+		default:
+			return fmt.Errorf("Resource [%s] has an unknown type [%s].", resource.Name, resource.Type)
+		}
+	}
+
+	return nil
+}
+
+// serviceTaskRole returns the role of a service's tasks, allowed to use the
+// queues it depends on. Services without queues get the default role.
+func serviceTaskRole(ctx *pulumi.Context, name string, svc jacuik_config.ServiceConfig, infra *Infrastructure) (*iam.Role, error) {
+	var queueArns []interface{}
+	for _, dependency := range svc.DependsOn {
+		if resource, ok := infra.Resources[dependency]; ok && resource.Type == jacuik_config.ResourceTypeQueue {
+			queueArns = append(queueArns, resource.Arn)
+		}
+	}
+
+	if len(queueArns) == 0 {
+		return nil, nil
+	}
+
+	return newTaskRole(ctx, fmt.Sprintf("%s-%s-task-role", name, svc.Name), nil, "queues", queueArns, []string{
+		"sqs:SendMessage",
+		"sqs:ReceiveMessage",
+		"sqs:DeleteMessage",
+		"sqs:ChangeMessageVisibility",
+		"sqs:GetQueueAttributes",
+		"sqs:GetQueueUrl",
+	})
+}
+
+// serviceSecrets stores the variables of a service in a Secrets Manager
+// secret, the database URLs hold its password and the env may hold
+// anything, so they aren't in plain text in the task definition. It returns
+// the container secrets reading the variables and the execution role
+// allowed to, there are none when the service has no variables.
+func serviceSecrets(ctx *pulumi.Context, name string, svc jacuik_config.ServiceConfig, variables map[string]pulumi.StringInput) (ecsx.TaskDefinitionSecretArray, *awsxgo.DefaultRoleWithPolicyArgs, error) {
+	if len(variables) == 0 {
+		return nil, nil, nil
+	}
+
+	names := sortedKeys(variables)
+	var values []interface{}
+	for _, variable := range names {
+		values = append(values, variables[variable])
+	}
+
+	secretString := pulumi.All(values...).ApplyT(func(values []interface{}) (string, error) {
+		contents := make(map[string]interface{})
+		for i, variable := range names {
+			contents[variable] = values[i]
+		}
+
+		result, err := json.Marshal(contents)
+		return string(result), err
+	}).(pulumi.StringOutput)
+
+	secret, err := secretsmanager.NewSecret(ctx, fmt.Sprintf("%s-%s-env", name, svc.Name), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = secretsmanager.NewSecretVersion(ctx, fmt.Sprintf("%s-%s-env", name, svc.Name), &secretsmanager.SecretVersionArgs{
+		SecretId:     secret.ID(),
+		SecretString: pulumi.ToSecret(secretString).(pulumi.StringOutput),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var secrets ecsx.TaskDefinitionSecretArray
+	for _, variable := range names {
+		secrets = append(secrets, ecsx.TaskDefinitionSecretArgs{
+			Name:      pulumi.String(variable),
+			ValueFrom: pulumi.Sprintf("%s:%s::", secret.Arn, variable),
+		})
+	}
+
+	executionRole, err := newTaskRole(ctx, fmt.Sprintf("%s-%s-execution-role", name, svc.Name), []string{taskExecutionPolicyArn}, "secrets", []interface{}{secret.Arn}, []string{
+		"secretsmanager:GetSecretValue",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secrets, &awsxgo.DefaultRoleWithPolicyArgs{RoleArn: executionRole.Arn}, nil
+}
+
+// newTaskRole creates a role assumed by ECS tasks, with the managed
+// policies and an inline policy allowing actions on resourceArns.
+func newTaskRole(ctx *pulumi.Context, roleName string, managedPolicyArns []string, policyName string, resourceArns []interface{}, actions []string) (*iam.Role, error) {
+	assumeRolePolicy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Service": "ecs-tasks.amazonaws.com"},
+			"Action":    "sts:AssumeRole",
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policy := pulumi.All(resourceArns...).ApplyT(func(arns []interface{}) (string, error) {
+		policy, err := json.Marshal(map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{{
+				"Effect":   "Allow",
+				"Action":   actions,
+				"Resource": arns,
+			}},
+		})
+		return string(policy), err
+	}).(pulumi.StringOutput)
+
+	args := &iam.RoleArgs{
+		AssumeRolePolicy: pulumi.String(assumeRolePolicy),
+		InlinePolicies: iam.RoleInlinePolicyArray{
+			iam.RoleInlinePolicyArgs{
+				Name:   pulumi.String(policyName),
+				Policy: policy,
+			},
+		},
+	}
+	if len(managedPolicyArns) > 0 {
+		args.ManagedPolicyArns = pulumi.ToStringArray(managedPolicyArns)
+	}
+
+	return iam.NewRole(ctx, roleName, args)
+}
+
+var invalidDatabaseNameCharacters = regexp.MustCompile(`[^a-z0-9_]`)
+
+// databaseName returns the name of the database created in a Postgres
+// resource, which can't contain dashes or start with a number.
+func databaseName(resourceName string) string {
+	name := invalidDatabaseNameCharacters.ReplaceAllString(resourceName, "_")
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		name = "db_" + name
+	}
+
+	return name
+}
+
+// ensureDatabasePassword generates the password of the Postgres resources
+// the first time a stack that has one is configured.
+func ensureDatabasePassword(ctx context.Context, stack auto.Stack, config *jacuik_config.AppConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	hasDatabase := false
+	for _, resource := range config.Resources {
+		hasDatabase = hasDatabase || resource.Type == jacuik_config.ResourceTypePostgres
+	}
+
+	if !hasDatabase {
+		return nil
+	}
+
+	stackConfig, err := stack.GetAllConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := stackConfig[databasePasswordKey]; ok {
+		return nil
+	}
+
+	password, err := randomPassword(32)
+	if err != nil {
+		return err
+	}
+
+	return stack.SetConfig(ctx, databasePasswordKey, auto.ConfigValue{Value: password, Secret: true})
+}
+
+const passwordCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// randomPassword returns a password that can be used in a URL as is.
+func randomPassword(length int) (string, error) {
+	password := make([]byte, length)
+	for i := range password {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordCharacters))))
+		if err != nil {
+			return "", err
+		}
+
+		password[i] = passwordCharacters[index.Int64()]
+	}
+
+	return string(password), nil
+}
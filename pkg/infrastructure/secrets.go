@@ -0,0 +1,186 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+// stackSettingsDirectory is where the stack settings are kept, relative to
+// the config file. Inline programs run in a new workspace every time, the
+// settings hold the encrypted key or salt of the stack's secrets so they
+// have to outlive it.
+const stackSettingsDirectory = ".jacuik/stacks"
+
+// stackSettingsPath returns the file the settings of the stack are kept in.
+func (i *InfrastructureHandler) stackSettingsPath() (string, error) {
+	projectDirectory, err := i.Config.ProjectDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(projectDirectory, stackSettingsDirectory, fmt.Sprintf("Pulumi.%s.yaml", defaultStackName)), nil
+}
+
+// loadStackSettings returns the saved settings of the stack, nil if there
+// are none.
+func (i *InfrastructureHandler) loadStackSettings() (*workspace.ProjectStack, error) {
+	settingsPath, err := i.stackSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return workspace.LoadProjectStack(settingsPath)
+}
+
+// saveStackSettings keeps the settings of the stack for the next workspace.
+func (i *InfrastructureHandler) saveStackSettings(ctx context.Context, stack auto.Stack) error {
+	settings, err := stackSettings(ctx, stack)
+	if err != nil || settings == nil {
+		return err
+	}
+
+	settingsPath, err := i.stackSettingsPath()
+	if err != nil {
+		return err
+	}
+
+	return settings.Save(settingsPath)
+}
+
+// stackSettings returns the settings in the stack's workspace, nil when it
+// has none. Stacks in Pulumi Cloud using its secrets provider have no
+// settings until config is set.
+func stackSettings(ctx context.Context, stack auto.Stack) (*workspace.ProjectStack, error) {
+	settings, err := stack.Workspace().StackSettings(ctx, stack.Name())
+	if err != nil {
+		// The automation API only tells a missing settings file apart by
+		// its message.
+		if strings.HasPrefix(err.Error(), "unable to find stack settings in workspace") {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("couldn't read the settings of stack [%s]: %w", stack.Name(), err)
+	}
+
+	return settings, nil
+}
+
+// stackSecretsProvider returns the secrets provider the stack settings use.
+func stackSecretsProvider(settings *workspace.ProjectStack) string {
+	switch {
+	case settings == nil:
+		return jacuik_config.SecretsProviderDefault
+	case settings.SecretsProvider != "" && settings.SecretsProvider != jacuik_config.SecretsProviderDefault:
+		return settings.SecretsProvider
+	case settings.EncryptionSalt != "":
+		return jacuik_config.SecretsProviderPassphrase
+	default:
+		return jacuik_config.SecretsProviderDefault
+	}
+}
+
+// expectedSecretsProvider returns the secrets provider the config asks for
+// as the stack settings show it. The default provider of a backend other
+// than Pulumi Cloud is a passphrase.
+func (i *InfrastructureHandler) expectedSecretsProvider() string {
+	if i.Config.SecretsProvider == jacuik_config.SecretsProviderDefault && i.Config.Backend.URL != "" && !i.Config.Backend.IsCloud() {
+		return jacuik_config.SecretsProviderPassphrase
+	}
+
+	return i.Config.SecretsProvider
+}
+
+// checkSecretsProvider returns an error when the stack encrypts its secrets
+// with another provider than the one in the config.
+func (i *InfrastructureHandler) checkSecretsProvider(ctx context.Context, stack auto.Stack) error {
+	if i.Config.SecretsProvider == "" {
+		return nil
+	}
+
+	settings, err := stackSettings(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	current := stackSecretsProvider(settings)
+	if current != i.expectedSecretsProvider() {
+		return fmt.Errorf("The stack's secrets are encrypted with [%s] but the config uses [%s]. Run `jacuik secrets rotate-provider` to re-encrypt them.", current, i.Config.SecretsProvider)
+	}
+
+	return nil
+}
+
+// RotateSecretsProvider re-encrypts the stack's secrets with the secrets
+// provider in the config. The output of the Pulumi CLI is written to
+// progressWriter.
+func (i *InfrastructureHandler) RotateSecretsProvider(progressWriter io.Writer) error {
+	if i.Config.SecretsProvider == "" {
+		return fmt.Errorf("The config has no secretsProvider to rotate to.")
+	}
+
+	ctx := context.Background()
+
+	opts, err := i.workspaceOptions()
+	if err != nil {
+		return err
+	}
+
+	// The stack is opened with the provider it was created with.
+	stack, err := auto.SelectStackInlineSource(ctx, i.stackName(), i.Name, i.BuildGraph, opts...)
+	if err != nil {
+		if auto.IsSelectStack404Error(err) {
+			return fmt.Errorf("The stack [%s] hasn't been created yet, the secrets provider is applied when it is.", i.stackName())
+		}
+
+		return err
+	}
+
+	settings, err := stackSettings(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	if stackSecretsProvider(settings) == i.expectedSecretsProvider() {
+		return fmt.Errorf("The stack's secrets are already encrypted with [%s].", i.Config.SecretsProvider)
+	}
+
+	// The automation API can't change the provider, the Pulumi CLI is run
+	// in the stack's workspace instead.
+	stackWorkspace := stack.Workspace()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "pulumi", "stack", "change-secrets-provider", i.Config.SecretsProvider, "--stack", stack.Name(), "--non-interactive")
+	cmd.Dir = stackWorkspace.WorkDir()
+	cmd.Stdout = progressWriter
+	cmd.Stderr = io.MultiWriter(progressWriter, &stderr)
+	cmd.Env = os.Environ()
+	for name, value := range stackWorkspace.GetEnvVars() {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", name, value))
+	}
+	if pulumiHome := stackWorkspace.PulumiHome(); pulumiHome != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("PULUMI_HOME=%s", pulumiHome))
+	}
+
+	err = cmd.Run()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("couldn't change the secrets provider: %s", message)
+		}
+		return fmt.Errorf("couldn't change the secrets provider: %w", err)
+	}
+
+	return i.saveStackSettings(ctx, stack)
+}
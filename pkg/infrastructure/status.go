@@ -0,0 +1,187 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+// ServiceStatus is the deployed state of a single service.
+type ServiceStatus struct {
+	Name         string
+	ServiceName  string
+	ImageURI     string
+	URL          string
+	DesiredCount int
+	// RunningCount is -1 when the number of running tasks couldn't be
+	// retrieved from the provider.
+	RunningCount int
+	// RunningCountError is why RunningCount couldn't be retrieved, if the
+	// provider tried.
+	RunningCountError error
+}
+
+// ImageTag returns the tag, or digest, of the deployed image.
+func (s ServiceStatus) ImageTag() string {
+	if at := strings.LastIndex(s.ImageURI, "@"); at != -1 {
+		return s.ImageURI[at+1:]
+	}
+
+	lastSegment := s.ImageURI[strings.LastIndex(s.ImageURI, "/")+1:]
+	if colon := strings.LastIndex(lastSegment, ":"); colon != -1 {
+		return lastSegment[colon+1:]
+	}
+
+	return ""
+}
+
+// Outputs returns the outputs of the deployed stack. If the stack has never
+// been deployed an empty map is returned.
+func (i *InfrastructureHandler) Outputs() (auto.OutputMap, error) {
+	ctx := context.Background()
+
+	opts, err := i.workspaceOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := auto.SelectStackInlineSource(ctx, i.stackName(), i.Name, i.BuildGraph, opts...)
+	if err != nil {
+		if auto.IsSelectStack404Error(err) {
+			return auto.OutputMap{}, nil
+		}
+
+		return nil, err
+	}
+
+	return stack.Outputs(ctx)
+}
+
+// StackOutputProvider resolves ${stack:name} expressions in the config to
+// the outputs of the deployed stack. The outputs are read the first time an
+// expression is resolved.
+func StackOutputProvider(name string) jacuik_config.InterpolationProvider {
+	var outputs auto.OutputMap
+	return func(projectDirectory, key string) (string, error) {
+		if outputs == nil {
+			// The config is still being resolved, only its backend is
+			// needed to find the stack.
+			config, err := backendConfig()
+			if err != nil {
+				return "", err
+			}
+
+			provider, err := NewProvider(name, config)
+			if err != nil {
+				return "", err
+			}
+
+			outputs, err = provider.Outputs()
+			if err != nil {
+				return "", fmt.Errorf("couldn't read the stack outputs: %w", err)
+			}
+		}
+
+		output, ok := outputs[key]
+		if !ok {
+			return "", fmt.Errorf("the stack has no output named %s", key)
+		}
+
+		if value, ok := output.Value.(string); ok {
+			return value, nil
+		}
+
+		value, err := json.Marshal(output.Value)
+		if err != nil {
+			return "", err
+		}
+
+		return string(value), nil
+	}
+}
+
+func backendConfig() (*jacuik_config.AppConfig, error) {
+	filePath, configType, err := jacuik_config.LocateConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := jacuik_config.LoadConfigFile(filePath, configType)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.DecodeBackend()
+}
+
+// ServiceStatuses returns the deployed state of every service in the stack
+// keyed by the service name from the config.
+func (i *InfrastructureHandler) ServiceStatuses() (map[string]ServiceStatus, error) {
+	outputs, err := i.Outputs()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]ServiceStatus)
+
+	servicesOutput, ok := outputs["services"]
+	if !ok {
+		return result, nil
+	}
+
+	services, ok := servicesOutput.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unexpected format for the services stack output.")
+	}
+
+	var serviceNames []string
+	for name, value := range services {
+		values, _ := value.(map[string]interface{})
+
+		status := ServiceStatus{
+			Name:         name,
+			RunningCount: -1,
+		}
+		status.ServiceName, _ = values["serviceName"].(string)
+		status.ImageURI, _ = values["imageUri"].(string)
+		status.URL, _ = values["url"].(string)
+		if desiredCount, ok := values["desiredCount"].(float64); ok {
+			status.DesiredCount = int(desiredCount)
+		}
+
+		result[name] = status
+		if status.ServiceName != "" {
+			serviceNames = append(serviceNames, status.ServiceName)
+		}
+	}
+
+	counter, ok := i.program.(runningCounter)
+	if !ok {
+		return result, nil
+	}
+
+	runningCounts, err := counter.RunningCounts(outputs, serviceNames)
+	if err != nil {
+		// The running counts are best effort, everything else comes from
+		// the stack so we can still report it along with the failure.
+		for name, status := range result {
+			status.RunningCountError = err
+			result[name] = status
+		}
+
+		return result, nil
+	}
+
+	for name, status := range result {
+		if count, ok := runningCounts[status.ServiceName]; ok {
+			status.RunningCount = count
+			result[name] = status
+		}
+	}
+
+	return result, nil
+}
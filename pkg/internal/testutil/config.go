@@ -0,0 +1,57 @@
+// Package testutil has the helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+// LoadConfig writes schema to a new project, with a Dockerfile in each of
+// servicePaths, and loads it. The test fails if the schema isn't valid. It
+// returns the config and the project directory.
+func LoadConfig(t *testing.T, schema string, servicePaths ...string) (*jacuik_config.AppConfig, string) {
+	t.Helper()
+
+	projectDirectory := t.TempDir()
+	var err error
+	for _, servicePath := range servicePaths {
+		err = os.MkdirAll(filepath.Join(projectDirectory, servicePath), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(filepath.Join(projectDirectory, servicePath, "Dockerfile"), []byte("FROM scratch\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schemaPath := filepath.Join(projectDirectory, "schema.yaml")
+	err = os.WriteFile(schemaPath, []byte(schema), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validationErrors, err := jacuik_config.ValidateConfigFile(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validationErrors) > 0 {
+		t.Fatalf("the test schema is invalid: %s", validationErrors)
+	}
+
+	file, err := jacuik_config.LoadConfigFile(schemaPath, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := file.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config, projectDirectory
+}
@@ -0,0 +1,141 @@
+package jacuik_config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// DefaultCloudBackendURL is the URL of Pulumi Cloud.
+const DefaultCloudBackendURL = "https://api.pulumi.com"
+
+// backendSchemes are the URL schemes Pulumi can keep state in.
+var backendSchemes = []string{"https", "http", "s3", "gs", "azblob", "file"}
+
+// The secrets providers that aren't a key URL. The default provider is
+// Pulumi Cloud's for stacks kept there and a passphrase otherwise.
+const (
+	SecretsProviderDefault    = "default"
+	SecretsProviderPassphrase = "passphrase"
+)
+
+// secretsProviderSchemes are the URL schemes of the key services Pulumi can
+// encrypt secrets with, like awskms://alias/my-key?region=us-west-2.
+var secretsProviderSchemes = []string{"awskms", "azurekeyvault", "gcpkms", "hashivault"}
+
+// BackendConfig is where the state of the deployed stack is kept.
+type BackendConfig struct {
+	URL          string `yaml:"url" json:"url" jsonschema:"required" description:"Where the state is kept: https://api.pulumi.com for Pulumi Cloud, s3://bucket for an S3 bucket or file://path for a local directory. Relative file paths are relative to the config file."`
+	Organization string `yaml:"organization,omitempty" json:"organization,omitempty" description:"The Pulumi Cloud organization the stack belongs to. Only used with Pulumi Cloud."`
+}
+
+// IsCloud reports whether the state is kept in Pulumi Cloud.
+func (b BackendConfig) IsCloud() bool {
+	return strings.HasPrefix(b.URL, "https://") || strings.HasPrefix(b.URL, "http://")
+}
+
+// validateBackendURL returns an error message when url isn't a backend
+// Pulumi supports, or an empty string.
+func validateBackendURL(backendURL string) string {
+	parsed, err := url.Parse(backendURL)
+	if err != nil || parsed.Scheme == "" {
+		return fmt.Sprintf("the backend url %q must start with one of %s://", backendURL, strings.Join(backendSchemes, "://, "))
+	}
+
+	for _, scheme := range backendSchemes {
+		if parsed.Scheme == scheme {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("the backend url %q must start with one of %s://", backendURL, strings.Join(backendSchemes, "://, "))
+}
+
+// validateSecretsProvider returns an error message when provider isn't a
+// secrets provider Pulumi supports, or an empty string.
+func validateSecretsProvider(provider string) string {
+	if provider == SecretsProviderDefault || provider == SecretsProviderPassphrase {
+		return ""
+	}
+
+	scheme, _, _ := strings.Cut(provider, "://")
+	for _, supported := range secretsProviderSchemes {
+		if scheme == supported {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("the secrets provider %q must be %q, %q or a key URL starting with one of %s://", provider, SecretsProviderDefault, SecretsProviderPassphrase, strings.Join(secretsProviderSchemes, "://, "))
+}
+
+// BackendURL returns the URL of the state backend, an empty string when the
+// config doesn't declare one and whatever Pulumi login is active is used.
+// A local directory is made absolute and created if it doesn't exist.
+func (a *AppConfig) BackendURL() (string, error) {
+	if a.Backend.URL == "" || !strings.HasPrefix(a.Backend.URL, "file://") {
+		return a.Backend.URL, nil
+	}
+
+	directory := strings.TrimPrefix(a.Backend.URL, "file://")
+	if strings.HasPrefix(directory, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		directory = filepath.Join(home, directory[1:])
+	}
+
+	if !filepath.IsAbs(directory) {
+		projectDirectory, err := a.ProjectDirectory()
+		if err != nil {
+			return "", err
+		}
+		directory = filepath.Join(projectDirectory, directory)
+	}
+
+	err := os.MkdirAll(directory, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("couldn't create the state directory [%s]: %w", directory, err)
+	}
+
+	return "file://" + filepath.ToSlash(directory), nil
+}
+
+// DecodeBackend returns a config holding only the provider and the backend
+// declared in the file, without resolving the rest of the config. Reading
+// the stack's outputs needs them, so they can't use ${stack:...}.
+func (c *ConfigFile) DecodeBackend() (*AppConfig, error) {
+	config := &AppConfig{file: c}
+
+	if provider := mappingValue(c.document.Content[0], "provider"); provider != nil && provider.Kind == yaml.ScalarNode {
+		config.Provider = provider.Value
+	}
+
+	node := mappingValue(c.document.Content[0], "backend")
+	if node == nil || isNull(node) {
+		return config, nil
+	}
+
+	r := newResolver(c)
+	r.excludedProviders = map[string]string{
+		"stack": "the backend can't use stack outputs, they are read from the backend",
+	}
+
+	copied := copyNode(node)
+	r.result = &resolvedConfig{root: copied, nodeFiles: make(map[*yaml.Node]string)}
+	r.resolveNode(copied, filepath.Base(c.Path))
+	if len(r.result.errors) > 0 {
+		return nil, r.result.errors
+	}
+
+	err := copied.Decode(&config.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
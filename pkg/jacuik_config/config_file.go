@@ -0,0 +1,678 @@
+package jacuik_config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	defaultYAMLIndent = 4
+	defaultJSONIndent = "    "
+)
+
+// ConfigFile is a config file loaded from disk. The file is kept as a
+// yaml.v3 node tree, yaml.v3 parses JSON as well, so that changes to the
+// config can be written back without losing comments, key ordering or
+// formatting.
+type ConfigFile struct {
+	Path string
+	Type string
+
+	document        *yaml.Node
+	contents        []byte
+	yamlIndent      int
+	jsonIndent      string
+	trailingNewline bool
+	// migratedFrom is the version the file was at before it was migrated
+	// in memory, zero when it wasn't.
+	migratedFrom int
+
+	// includes are the files included by this file, loaded when the
+	// config is decoded.
+	includes []*ConfigFile
+}
+
+// LoadConfigFile reads a config file of the given type from disk.
+func LoadConfigFile(filePath, typ string) (*ConfigFile, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseConfigFile(filePath, typ, contents)
+}
+
+func parseConfigFile(filePath, typ string, contents []byte) (*ConfigFile, error) {
+	var document yaml.Node
+	err := parseDocument(typ, contents, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("The schema file [%s] is empty.", filePath)
+	}
+
+	return &ConfigFile{
+		Path:            filePath,
+		Type:            typ,
+		document:        &document,
+		contents:        contents,
+		yamlIndent:      detectYAMLIndent(contents),
+		jsonIndent:      detectJSONIndent(contents),
+		trailingNewline: bytes.HasSuffix(contents, []byte("\n")),
+	}, nil
+}
+
+// ParseDocument parses the contents of a config file into a node tree. The
+// type of the file is taken from its extension.
+func ParseDocument(filePath string, contents []byte) (*yaml.Node, error) {
+	typ, err := configTypeFromPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	err = parseDocument(typ, contents, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// parseDocument parses a config file of the given type. yaml.v3 parses JSON
+// as well, TOML is converted to the same node tree.
+func parseDocument(typ string, contents []byte, document *yaml.Node) error {
+	if typ == "toml" {
+		return parseTOML(contents, document)
+	}
+
+	return yaml.Unmarshal(contents, document)
+}
+
+// Decode returns the config held in the file, with the files it includes
+// merged in and its ${...} expressions resolved.
+func (c *ConfigFile) Decode() (*AppConfig, error) {
+	resolved := c.resolve()
+	if len(resolved.errors) > 0 {
+		return nil, resolved.errors
+	}
+
+	config := new(AppConfig)
+	err := resolved.root.Decode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range config.Services {
+		if i < len(resolved.serviceFiles) {
+			config.Services[i].file = resolved.serviceFiles[i]
+		}
+	}
+	for i := range config.Resources {
+		if i < len(resolved.resourceFiles) {
+			config.Resources[i].file = resolved.resourceFiles[i]
+		}
+	}
+
+	config.file = c
+	return config, nil
+}
+
+// Version returns the version of the config file format used by the file.
+func (c *ConfigFile) Version() (int, error) {
+	version, _, err := DocumentVersion(c.document.Content[0])
+	return version, err
+}
+
+// Migrate upgrades the file to the current version in memory, Save writes
+// the upgraded file.
+func (c *ConfigFile) Migrate() ([]Migration, error) {
+	version, err := c.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := MigrateDocument(c.document.Content[0])
+	if len(applied) > 0 {
+		c.migratedFrom = version
+	}
+
+	return applied, err
+}
+
+// Update changes the file, and the files it includes, to match config.
+// Existing nodes are edited in place so comments and formatting around them
+// survive. Services and resources are written to the file they were
+// declared in, new ones are added to this file.
+func (c *ConfigFile) Update(config *AppConfig) error {
+	s := syncer{interpolate: newResolver(c).interpolate}
+
+	own := *config
+	own.Services = servicesIn(config.Services, c, true)
+	own.Resources = resourcesIn(config.Resources, c, true)
+	err := s.syncNode(c.document.Content[0], reflect.ValueOf(own))
+	if err != nil {
+		return err
+	}
+
+	for _, included := range c.includes {
+		var includedOwn includedConfig
+		if includeNode := mappingValue(included.document.Content[0], "include"); includeNode != nil {
+			err = includeNode.Decode(&includedOwn.Include)
+			if err != nil {
+				return err
+			}
+		}
+
+		includedOwn.Services = servicesIn(config.Services, included, false)
+		includedOwn.Resources = resourcesIn(config.Resources, included, false)
+		err = s.syncNode(included.document.Content[0], reflect.ValueOf(includedOwn))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// servicesIn returns the services declared in file. New services belong to
+// the root file.
+func servicesIn(services []ServiceConfig, file *ConfigFile, root bool) []ServiceConfig {
+	var result []ServiceConfig
+	for _, svc := range services {
+		if svc.file == file || root && svc.file == nil {
+			result = append(result, svc)
+		}
+	}
+
+	return result
+}
+
+func resourcesIn(resources []ResourceConfig, file *ConfigFile, root bool) []ResourceConfig {
+	var result []ResourceConfig
+	for _, resource := range resources {
+		if resource.file == file || root && resource.file == nil {
+			result = append(result, resource)
+		}
+	}
+
+	return result
+}
+
+// Marshal returns the contents of the file.
+func (c *ConfigFile) Marshal() ([]byte, error) {
+	if c.Type == "toml" {
+		var buffer bytes.Buffer
+		writeTOMLNode(&buffer, c.document)
+		return buffer.Bytes(), nil
+	}
+
+	if c.Type == "json" {
+		var buffer bytes.Buffer
+		writeJSONNode(&buffer, c.document.Content[0], c.jsonIndent, 0)
+		if c.trailingNewline {
+			buffer.WriteString("\n")
+		}
+
+		return buffer.Bytes(), nil
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(c.yamlIndent)
+
+	err := encoder.Encode(c.document)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Save writes the file, and any included file that changed, back to disk.
+func (c *ConfigFile) Save() error {
+	contents, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(contents, c.contents) {
+		err = os.WriteFile(c.Path, contents, 0644)
+		if err != nil {
+			return err
+		}
+		c.contents = contents
+	}
+
+	for _, included := range c.includes {
+		err = included.Save()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncer edits a node tree to match a value.
+type syncer struct {
+	// interpolate resolves the ${...} expressions in a scalar, so values
+	// that still resolve to the same thing are left alone.
+	interpolate func(value string) (string, error)
+}
+
+// syncNode updates node in place so it holds value.
+func (s syncer) syncNode(node *yaml.Node, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return replaceNode(node, value)
+		}
+
+		typ := value.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			tagParts := strings.Split(field.Tag.Get("yaml"), ",")
+			name := tagParts[0]
+			if name == "" || name == "-" {
+				continue
+			}
+
+			omitEmpty := len(tagParts) > 1 && tagParts[1] == "omitempty"
+			fieldValue := value.Field(i)
+
+			keyIndex := -1
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == name {
+					keyIndex = j
+					break
+				}
+			}
+
+			if fieldValue.IsZero() && (omitEmpty || keyIndex == -1) {
+				// Drop empty optional fields, and don't add empty fields
+				// that weren't in the file to begin with.
+				if keyIndex != -1 {
+					node.Content = append(node.Content[:keyIndex], node.Content[keyIndex+2:]...)
+				}
+				continue
+			}
+
+			if keyIndex != -1 {
+				err := s.syncNode(node.Content[keyIndex+1], fieldValue)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			valueNode := new(yaml.Node)
+			err := valueNode.Encode(fieldValue.Interface())
+			if err != nil {
+				return err
+			}
+
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+			if node.Style&yaml.FlowStyle != 0 || len(node.Content) > 0 && node.Content[0].Style&yaml.DoubleQuotedStyle != 0 {
+				keyNode.Style = yaml.DoubleQuotedStyle
+			}
+
+			node.Content = append(node.Content, keyNode, valueNode)
+		}
+
+		return nil
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+				node.Kind = yaml.SequenceNode
+				node.Tag = "!!seq"
+				node.Value = ""
+			} else {
+				return replaceNode(node, value)
+			}
+		}
+
+		existing := node.Content
+		used := make([]bool, len(existing))
+
+		newNames := make(map[string]bool)
+		for i := 0; i < value.Len(); i++ {
+			if name := elementName(value.Index(i)); name != "" {
+				newNames[name] = true
+			}
+		}
+
+		var content []*yaml.Node
+		for i := 0; i < value.Len(); i++ {
+			element := value.Index(i)
+			name := elementName(element)
+
+			// Reuse the node for the element with the same name. When an
+			// element was renamed fall back to the node at the same index.
+			match := -1
+			if name != "" {
+				for j, item := range existing {
+					if !used[j] && nodeName(item) == name {
+						match = j
+						break
+					}
+				}
+			}
+			if match == -1 && i < len(existing) && !used[i] && !newNames[nodeName(existing[i])] {
+				match = i
+			}
+
+			if match == -1 {
+				item := new(yaml.Node)
+				err := item.Encode(element.Interface())
+				if err != nil {
+					return err
+				}
+				content = append(content, item)
+				continue
+			}
+
+			used[match] = true
+			err := s.syncNode(existing[match], element)
+			if err != nil {
+				return err
+			}
+			content = append(content, existing[match])
+		}
+
+		node.Content = content
+		if len(content) == 0 {
+			node.Style |= yaml.FlowStyle
+		}
+		return nil
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return replaceNode(node, value)
+		}
+
+		// Update and remove the existing keys in place, then add new keys
+		// in sorted order.
+		seen := make(map[string]bool)
+		var content []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			element := value.MapIndex(reflect.ValueOf(key.Value))
+			if !element.IsValid() {
+				continue
+			}
+
+			err := s.syncNode(node.Content[i+1], element)
+			if err != nil {
+				return err
+			}
+
+			seen[key.Value] = true
+			content = append(content, key, node.Content[i+1])
+		}
+
+		var newKeys []string
+		for _, key := range value.MapKeys() {
+			if !seen[key.String()] {
+				newKeys = append(newKeys, key.String())
+			}
+		}
+		sort.Strings(newKeys)
+
+		for _, key := range newKeys {
+			valueNode := new(yaml.Node)
+			err := valueNode.Encode(value.MapIndex(reflect.ValueOf(key)).Interface())
+			if err != nil {
+				return err
+			}
+
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			if node.Style&yaml.FlowStyle != 0 || len(node.Content) > 0 && node.Content[0].Style&yaml.DoubleQuotedStyle != 0 {
+				keyNode.Style = yaml.DoubleQuotedStyle
+			}
+			content = append(content, keyNode, valueNode)
+		}
+
+		node.Content = content
+		return nil
+
+	default:
+		if node.Kind == yaml.ScalarNode {
+			current := reflect.New(value.Type())
+			if node.Decode(current.Interface()) == nil && reflect.DeepEqual(current.Elem().Interface(), value.Interface()) {
+				// Leave the node alone so its quoting and formatting is kept.
+				return nil
+			}
+
+			// Keep ${...} expressions that still resolve to the value.
+			if strings.Contains(node.Value, "${") && s.interpolate != nil {
+				resolved, err := s.interpolate(node.Value)
+				resolvedNode := &yaml.Node{Kind: yaml.ScalarNode, Value: resolved}
+				if err == nil && resolvedNode.Decode(current.Interface()) == nil && reflect.DeepEqual(current.Elem().Interface(), value.Interface()) {
+					return nil
+				}
+			}
+
+			switch value.Kind() {
+			case reflect.Bool:
+				node.Tag = "!!bool"
+				node.Value = strconv.FormatBool(value.Bool())
+			case reflect.Int, reflect.Int64, reflect.Int32:
+				node.Tag = "!!int"
+				node.Value = strconv.FormatInt(value.Int(), 10)
+			default:
+				node.Tag = "!!str"
+				node.Value = value.String()
+			}
+			return nil
+		}
+
+		return replaceNode(node, value)
+	}
+}
+
+// replaceNode overwrites node with a freshly encoded value, keeping any
+// comments attached to it.
+func replaceNode(node *yaml.Node, value reflect.Value) error {
+	replacement := new(yaml.Node)
+	err := replacement.Encode(value.Interface())
+	if err != nil {
+		return err
+	}
+
+	replacement.HeadComment = node.HeadComment
+	replacement.LineComment = node.LineComment
+	replacement.FootComment = node.FootComment
+	*node = *replacement
+
+	return nil
+}
+
+// elementName returns the Name field of a struct, used to match list items.
+func elementName(value reflect.Value) string {
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+
+	name := value.FieldByName("Name")
+	if !name.IsValid() || name.Kind() != reflect.String {
+		return ""
+	}
+
+	return name.String()
+}
+
+func nodeName(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "name" {
+			return node.Content[i+1].Value
+		}
+	}
+
+	return ""
+}
+
+// detectYAMLIndent returns the smallest indentation used in the file.
+func detectYAMLIndent(contents []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(contents), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		lineIndent := len(line) - len(trimmed)
+		if lineIndent > 0 && (indent == 0 || lineIndent < indent) {
+			indent = lineIndent
+		}
+	}
+
+	if indent < 2 {
+		return defaultYAMLIndent
+	}
+
+	return indent
+}
+
+// detectJSONIndent returns the whitespace used to indent the first nested line.
+func detectJSONIndent(contents []byte) string {
+	for _, line := range strings.Split(string(contents), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+
+	return defaultJSONIndent
+}
+
+// writeJSONNode writes a node as JSON keeping the order of mapping keys.
+func writeJSONNode(buffer *bytes.Buffer, node *yaml.Node, indent string, depth int) {
+	newline := func(depth int) {
+		buffer.WriteString("\n")
+		buffer.WriteString(strings.Repeat(indent, depth))
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			writeJSONNode(buffer, node.Content[0], indent, depth)
+		}
+	case yaml.AliasNode:
+		writeJSONNode(buffer, node.Alias, indent, depth)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buffer.WriteString("{}")
+			return
+		}
+
+		if onOneLine(node, node.Line) {
+			buffer.WriteString("{")
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if i > 0 {
+					buffer.WriteString(", ")
+				}
+				writeJSONString(buffer, node.Content[i].Value)
+				buffer.WriteString(": ")
+				writeJSONNode(buffer, node.Content[i+1], indent, depth+1)
+			}
+			buffer.WriteString("}")
+			return
+		}
+
+		buffer.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			newline(depth + 1)
+			writeJSONString(buffer, node.Content[i].Value)
+			buffer.WriteString(": ")
+			writeJSONNode(buffer, node.Content[i+1], indent, depth+1)
+		}
+		newline(depth)
+		buffer.WriteString("}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buffer.WriteString("[]")
+			return
+		}
+
+		if onOneLine(node, node.Line) {
+			buffer.WriteString("[")
+			for i, item := range node.Content {
+				if i > 0 {
+					buffer.WriteString(", ")
+				}
+				writeJSONNode(buffer, item, indent, depth+1)
+			}
+			buffer.WriteString("]")
+			return
+		}
+
+		buffer.WriteString("[")
+		for i, item := range node.Content {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			newline(depth + 1)
+			writeJSONNode(buffer, item, indent, depth+1)
+		}
+		newline(depth)
+		buffer.WriteString("]")
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			buffer.WriteString(node.Value)
+		case "!!null":
+			buffer.WriteString("null")
+		default:
+			writeJSONString(buffer, node.Value)
+		}
+	}
+}
+
+// onOneLine reports whether a node, and everything in it, was read from a
+// single line of the original file. New nodes have no line and never are.
+func onOneLine(node *yaml.Node, line int) bool {
+	if node.Line == 0 || node.Line != line {
+		return false
+	}
+
+	for _, child := range node.Content {
+		if !onOneLine(child, line) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeJSONString(buffer *bytes.Buffer, value string) {
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+
+	// json.Encoder always adds a newline after the value.
+	buffer.Truncate(buffer.Len() - 1)
+}
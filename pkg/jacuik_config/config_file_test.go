@@ -0,0 +1,329 @@
+package jacuik_config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateKeepsComments(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		config string
+		edit   func(config *AppConfig) error
+		want   string
+	}{
+		{
+			name: "yaml field change",
+			file: "schema.yaml",
+			config: `# The demo project.
+version: 2
+name: demo
+services:
+  # The public API.
+  - name: api
+    path: ./api # built from its own directory
+    public: true
+`,
+			edit: func(config *AppConfig) error {
+				svc, _ := config.GetService("api")
+				svc.Port = 9000
+				return nil
+			},
+			want: `# The demo project.
+version: 2
+name: demo
+services:
+  # The public API.
+  - name: api
+    path: ./api # built from its own directory
+    public: true
+    port: 9000
+`,
+		},
+		{
+			name: "yaml add and remove",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+services:
+  # Going away.
+  - name: old
+    path: ./api
+  # Staying.
+  - name: api
+    path: ./api # the API
+`,
+			edit: func(config *AppConfig) error {
+				config.AddService(ServiceConfig{Name: "web", PathToDockerfile: "./api", Public: true})
+				return config.RemoveService("old")
+			},
+			want: `version: 2
+name: demo
+services:
+  # Staying.
+  - name: api
+    path: ./api # the API
+  - name: web
+    path: ./api
+    public: true
+`,
+		},
+		{
+			name: "yaml rename",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+services:
+  - name: api # renamed below
+    path: ./api
+  - name: worker
+    path: ./api
+    kind: worker
+    dependsOn:
+      - api # needs the API
+`,
+			edit: func(config *AppConfig) error {
+				return config.RenameService("api", "backend")
+			},
+			want: `version: 2
+name: demo
+services:
+  - name: backend # renamed below
+    path: ./api
+  - name: worker
+    path: ./api
+    kind: worker
+    dependsOn:
+      - backend # needs the API
+`,
+		},
+		{
+			name: "toml",
+			file: "schema.toml",
+			config: `# The demo project.
+version = 2
+name = "demo"
+
+# The public API.
+[[services]]
+name = "api"
+path = "./api" # built from its own directory
+`,
+			edit: func(config *AppConfig) error {
+				svc, _ := config.GetService("api")
+				svc.Public = true
+				return nil
+			},
+			want: `# The demo project.
+version = 2
+name = "demo"
+
+# The public API.
+[[services]]
+name = "api"
+path = "./api" # built from its own directory
+public = true
+`,
+		},
+		{
+			name: "json keeps order and indent",
+			file: "schema.json",
+			config: `{
+  "name": "demo",
+  "version": 2,
+  "services": [
+    {
+      "name": "api",
+      "path": "./api"
+    }
+  ],
+  "resources": [{"name": "db", "type": "postgres"}]
+}
+`,
+			edit: func(config *AppConfig) error {
+				svc, _ := config.GetService("api")
+				svc.Public = true
+				return nil
+			},
+			want: `{
+  "name": "demo",
+  "version": 2,
+  "services": [
+    {
+      "name": "api",
+      "path": "./api",
+      "public": true
+    }
+  ],
+  "resources": [{"name": "db", "type": "postgres"}]
+}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeProject(t, map[string]string{
+				test.file:        test.config,
+				"api/Dockerfile": "FROM scratch\n",
+			})
+			filePath := filepath.Join(dir, test.file)
+
+			typ, err := configTypeFromPath(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := LoadConfigFile(filePath, typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := file.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := test.edit(config); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := config.SaveConfigFile(); err != nil {
+				t.Fatal(err)
+			}
+
+			contents, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(contents) != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", contents, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateIncludedFile(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"schema.yaml": `version: 2
+name: demo
+include:
+  - ./workers.yaml
+services:
+  - name: api
+    path: ./api
+`,
+		"workers.yaml": `# Background workers.
+services:
+  - name: worker # processes jobs
+    path: ./api
+    kind: worker
+`,
+		"api/Dockerfile": "FROM scratch\n",
+	})
+
+	file, err := LoadConfigFile(filepath.Join(dir, "schema.yaml"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := file.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, _ := config.GetService("worker")
+	svc.Port = 9000
+
+	if err := config.SaveConfigFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Background workers.
+services:
+  - name: worker # processes jobs
+    path: ./api
+    kind: worker
+    port: 9000
+`
+	contents, err := os.ReadFile(filepath.Join(dir, "workers.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", contents, want)
+	}
+}
+
+func TestDeletableServiceDirectory(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"schema.yaml": `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+  - name: admin
+    path: ./api
+  - name: web
+    path: ./web
+  - name: jobs
+    path: ./web/jobs
+  - name: worker
+    path: ./worker
+  - name: root
+    path: .
+  - name: shared
+    path: ../shared
+`,
+		"api/Dockerfile":      "FROM scratch\n",
+		"web/Dockerfile":      "FROM scratch\n",
+		"web/jobs/Dockerfile": "FROM scratch\n",
+		"worker/Dockerfile":   "FROM scratch\n",
+	})
+
+	file, err := LoadConfigFile(filepath.Join(dir, "schema.yaml"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := file.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		service string
+		want    string
+		err     string
+	}{
+		{service: "worker", want: filepath.Join(dir, "worker")},
+		{service: "jobs", want: filepath.Join(dir, "web", "jobs")},
+		{service: "api", err: "The directory [./api] of service [api] is also used by [admin]."},
+		{service: "web", err: "The directory [./web] of service [web] is also used by [jobs]."},
+		{service: "root", err: "Service [root] is built from [.] which isn't a directory in the project."},
+		{service: "shared", err: "Service [shared] is built from [../shared] which isn't a directory in the project."},
+		{service: "missing", err: "Service [missing] does not exist."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.service, func(t *testing.T) {
+			got, err := config.DeletableServiceDirectory(test.service)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package jacuik_config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// configPath is a location in the config reached by a dotted path like
+// services.api.port. Services and resources are addressed by name.
+type configPath struct {
+	path  string
+	value reflect.Value
+	// parent and key are set when the location is an entry of a list or
+	// a map. A map entry that isn't set has an invalid value.
+	parent reflect.Value
+	key    string
+	// field is set when the location is a field of a struct.
+	field *reflect.StructField
+}
+
+func (a *AppConfig) walkPath(path string) (*configPath, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("A path into the config is required, e.g. services.api.port.")
+	}
+
+	parts := strings.Split(path, ".")
+	current := &configPath{value: reflect.ValueOf(a).Elem()}
+
+	for i, part := range parts {
+		location := strings.Join(parts[:i+1], ".")
+		value := current.value
+
+		if !value.IsValid() {
+			return nil, fmt.Errorf("[%s] is not set.", current.path)
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			field, ok := yamlFieldNames(value.Type())[part]
+			if !ok {
+				return nil, fmt.Errorf("Unknown field [%s].", location)
+			}
+
+			current = &configPath{path: location, value: value.FieldByIndex(field.Index), field: &field}
+
+		case reflect.Slice:
+			if value.Type().Elem().Kind() != reflect.Struct {
+				return nil, fmt.Errorf("[%s] is a list of values and has no fields.", current.path)
+			}
+
+			match := -1
+			for j := 0; j < value.Len(); j++ {
+				if elementName(value.Index(j)) == part {
+					match = j
+					break
+				}
+			}
+			if match == -1 {
+				return nil, fmt.Errorf("[%s] does not exist.", location)
+			}
+
+			current = &configPath{path: location, value: value.Index(match), parent: value, key: part}
+
+		case reflect.Map:
+			current = &configPath{path: location, value: value.MapIndex(reflect.ValueOf(part)), parent: value, key: part}
+
+		default:
+			return nil, fmt.Errorf("[%s] has no fields.", current.path)
+		}
+	}
+
+	return current, nil
+}
+
+// GetValue returns the value at a dotted path into the config, with any
+// ${...} expressions resolved.
+func (a *AppConfig) GetValue(path string) (interface{}, error) {
+	location, err := a.walkPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !location.value.IsValid() {
+		return nil, fmt.Errorf("[%s] is not set.", path)
+	}
+
+	return location.value.Interface(), nil
+}
+
+// SetValue sets the value at a dotted path into the config. The value is
+// converted to the type of the field, lists are comma separated.
+func (a *AppConfig) SetValue(path, value string) error {
+	location, err := a.walkPath(path)
+	if err != nil {
+		return err
+	}
+
+	// Renaming a service updates the services that depend on it.
+	if parts := strings.Split(path, "."); len(parts) == 3 && parts[0] == "services" && parts[2] == "name" {
+		return a.RenameService(parts[1], value)
+	}
+
+	var typ reflect.Type
+	if location.parent.IsValid() && location.parent.Kind() == reflect.Map {
+		typ = location.parent.Type().Elem()
+	} else {
+		typ = location.value.Type()
+	}
+
+	converted, err := convertValue(path, typ, value)
+	if err != nil {
+		return err
+	}
+
+	if location.parent.IsValid() && location.parent.Kind() == reflect.Map {
+		if location.parent.IsNil() {
+			location.parent.Set(reflect.MakeMap(location.parent.Type()))
+		}
+		location.parent.SetMapIndex(reflect.ValueOf(location.key), converted)
+		return nil
+	}
+
+	location.value.Set(converted)
+	return nil
+}
+
+// UnsetValue removes the value at a dotted path from the config. Services
+// and resources are removed when nothing depends on them.
+func (a *AppConfig) UnsetValue(path string) error {
+	location, err := a.walkPath(path)
+	if err != nil {
+		return err
+	}
+
+	if !location.parent.IsValid() {
+		if _, required := parseJSONSchemaTag(location.field.Tag.Get("jsonschema"))["required"]; required {
+			return fmt.Errorf("[%s] is required and can't be unset.", path)
+		}
+
+		location.value.Set(reflect.Zero(location.value.Type()))
+		return nil
+	}
+
+	switch location.parent.Kind() {
+	case reflect.Map:
+		if !location.value.IsValid() {
+			return fmt.Errorf("[%s] is not set.", path)
+		}
+		location.parent.SetMapIndex(reflect.ValueOf(location.key), reflect.Value{})
+		return nil
+
+	default:
+		switch location.value.Type() {
+		case reflect.TypeOf(ServiceConfig{}):
+			return a.RemoveService(location.key)
+		case reflect.TypeOf(ResourceConfig{}):
+			return a.RemoveResource(location.key)
+		default:
+			return fmt.Errorf("[%s] can't be unset.", path)
+		}
+	}
+}
+
+// convertValue converts a value given on the command line to typ.
+func convertValue(path string, typ reflect.Type, value string) (reflect.Value, error) {
+	switch typ.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(value).Convert(typ), nil
+	case reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("[%s] must be a whole number, got [%s].", path, value)
+		}
+		return reflect.ValueOf(number).Convert(typ), nil
+	case reflect.Bool:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("[%s] must be true or false, got [%s].", path, value)
+		}
+		return reflect.ValueOf(boolean), nil
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("[%s] can't be set directly, set the fields of its entries instead.", path)
+		}
+
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return reflect.ValueOf(items), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("[%s] can't be set directly, set one of its fields instead.", path)
+	}
+}
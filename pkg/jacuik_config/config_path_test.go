@@ -0,0 +1,233 @@
+package jacuik_config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const pathConfig = `version: 2
+# The demo project.
+name: demo
+services:
+  # The public API.
+  - name: api
+    path: ./api # built from its own directory
+    public: true
+    dependsOn: [db]
+    env:
+      LOG_LEVEL: debug # noisy
+  - name: worker
+    path: ./api
+    kind: worker
+resources:
+  - name: db # the database
+    type: postgres
+`
+
+// loadPathConfig writes pathConfig to a project and loads it.
+func loadPathConfig(t *testing.T) (*AppConfig, string) {
+	t.Helper()
+
+	dir := writeProject(t, map[string]string{
+		"schema.yaml":    pathConfig,
+		"api/Dockerfile": "FROM scratch\n",
+	})
+	filePath := filepath.Join(dir, "schema.yaml")
+
+	file, err := LoadConfigFile(filePath, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := file.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config, filePath
+}
+
+func TestGetValue(t *testing.T) {
+	tests := []struct {
+		path string
+		want interface{}
+		err  string
+	}{
+		{path: "name", want: "demo"},
+		{path: "services.api.public", want: true},
+		{path: "services.api.port", want: 0},
+		{path: "services.api.dependsOn", want: []string{"db"}},
+		{path: "services.api.env.LOG_LEVEL", want: "debug"},
+		{path: "resources.db.type", want: "postgres"},
+		{path: "", err: "A path into the config is required, e.g. services.api.port."},
+		{path: "nmae", err: "Unknown field [nmae]."},
+		{path: "services.web.port", err: "[services.web] does not exist."},
+		{path: "services.api.env.MISSING", err: "[services.api.env.MISSING] is not set."},
+		{path: "services.api.dependsOn.db", err: "[services.api.dependsOn] is a list of values and has no fields."},
+		{path: "name.first", err: "[name] has no fields."},
+	}
+
+	config, _ := loadPathConfig(t)
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			got, err := config.GetValue(test.path)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		path  string
+		value string
+		// want is the value read back from path, or from wantPath.
+		want     interface{}
+		wantPath string
+		err      string
+	}{
+		{path: "description", value: "The demo", want: "The demo"},
+		{path: "services.api.port", value: "9000", want: 9000},
+		{path: "services.api.public", value: "false", want: false},
+		{path: "services.api.dependsOn", value: "db, worker,", want: []string{"db", "worker"}},
+		{path: "services.api.dependsOn", value: "", want: []string{}},
+		{path: "services.worker.env.QUEUE", value: "jobs", want: "jobs"},
+		{path: "vars.region", value: "us-east-1", want: "us-east-1"},
+		{path: "services.worker.name", value: "jobs-worker", wantPath: "services.jobs-worker.kind", want: "worker"},
+		{path: "services.api.port", value: "eighty", err: "[services.api.port] must be a whole number, got [eighty]."},
+		{path: "services.api.public", value: "yes please", err: "[services.api.public] must be true or false, got [yes please]."},
+		{path: "services", value: "api", err: "[services] can't be set directly, set the fields of its entries instead."},
+		{path: "backend", value: "file://.", err: "[backend] can't be set directly, set one of its fields instead."},
+		{path: "services.api.name", value: "Api", err: "The service name [Api] may only contain lowercase letters, numbers and dashes, and can't start or end with a dash."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path+"="+test.value, func(t *testing.T) {
+			config, _ := loadPathConfig(t)
+
+			err := config.SetValue(test.path, test.value)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := test.path
+			if test.wantPath != "" {
+				path = test.wantPath
+			}
+
+			got, err := config.GetValue(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnsetValue(t *testing.T) {
+	tests := []struct {
+		path string
+		// unset is the path that must no longer be set afterwards.
+		unset string
+		err   string
+	}{
+		{path: "services.api.env.LOG_LEVEL", unset: "services.api.env.LOG_LEVEL"},
+		{path: "services.api.public", unset: ""},
+		{path: "services.worker", unset: "services.worker"},
+		{path: "name", err: "[name] is required and can't be unset."},
+		{path: "services.api.env.MISSING", err: "[services.api.env.MISSING] is not set."},
+		{path: "resources.db", err: "Resource [db] is still referenced by [api]."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			config, _ := loadPathConfig(t)
+
+			err := config.UnsetValue(test.path)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.unset != "" {
+				if _, err := config.GetValue(test.unset); err == nil {
+					t.Errorf("%s is still set", test.unset)
+				}
+			}
+		})
+	}
+}
+
+func TestSetValueKeepsComments(t *testing.T) {
+	config, filePath := loadPathConfig(t)
+
+	for path, value := range map[string]string{
+		"services.api.port":          "9000",
+		"services.api.env.LOG_LEVEL": "info",
+	} {
+		if err := config.SetValue(path, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := config.UnsetValue("services.worker"); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.UnsetValue("services.api.public"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.SaveConfigFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `version: 2
+# The demo project.
+name: demo
+services:
+  # The public API.
+  - name: api
+    path: ./api # built from its own directory
+    public: false
+    dependsOn: [db]
+    env:
+      LOG_LEVEL: info # noisy
+    port: 9000
+resources:
+  - name: db # the database
+    type: postgres
+`
+	if string(contents) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", contents, want)
+	}
+}
@@ -0,0 +1,119 @@
+package jacuik_config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ConfigTypes are the formats the config file can be written in.
+var ConfigTypes = []string{"yaml", "json", "toml"}
+
+// schemaComment returns the comment that points editors at the JSON Schema
+// for a file type. JSON files use the $schema field instead.
+func schemaComment(typ string) string {
+	switch typ {
+	case "yaml":
+		return fmt.Sprintf("# yaml-language-server: $schema=%s", JSONSchemaFilePath)
+	case "toml":
+		return fmt.Sprintf("#:schema %s", JSONSchemaFilePath)
+	default:
+		return ""
+	}
+}
+
+// Convert returns the file in another format, named schema.<type> in the
+// same directory. Comments are kept for YAML and TOML, JSON has none.
+// Included files are left in the format they are in.
+func (c *ConfigFile) Convert(typ string) (*ConfigFile, error) {
+	if !IsConfigType(typ) {
+		return nil, fmt.Errorf("Unknown config format [%s], expected one of %s.", typ, strings.Join(ConfigTypes, ", "))
+	}
+
+	document := copyNode(c.document)
+	resetStyle(document)
+
+	// Swap the pointer to the JSON Schema for the one the new format uses.
+	root := document.Content[0]
+	document.HeadComment = stripSchemaComments(document.HeadComment)
+	root.HeadComment = stripSchemaComments(root.HeadComment)
+	removeMappingKey(root, "$schema")
+	if len(root.Content) > 0 {
+		root.Content[0].HeadComment = stripSchemaComments(root.Content[0].HeadComment)
+	}
+
+	if typ == "json" {
+		root.Content = append([]*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "$schema"},
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: JSONSchemaFilePath},
+		}, root.Content...)
+	} else {
+		document.HeadComment = strings.Trim(schemaComment(typ)+"\n"+document.HeadComment, "\n")
+	}
+
+	return &ConfigFile{
+		Path:            filepath.Join(filepath.Dir(c.Path), "schema."+typ),
+		Type:            typ,
+		document:        document,
+		yamlIndent:      defaultYAMLIndent,
+		jsonIndent:      defaultJSONIndent,
+		trailingNewline: true,
+	}, nil
+}
+
+// IsConfigType reports whether typ is a supported config format.
+func IsConfigType(typ string) bool {
+	for _, configType := range ConfigTypes {
+		if configType == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resetStyle drops the quoting and flow styles of the original format so
+// the new format is written in its usual style. Lists of scalars are kept
+// on one line.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.SequenceNode && len(node.Content) > 0 {
+		inline := true
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				inline = false
+			}
+		}
+		if inline {
+			node.Style = yaml.FlowStyle
+		}
+	}
+
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+func stripSchemaComments(comment string) string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "# yaml-language-server:") || strings.HasPrefix(trimmed, "#:schema") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
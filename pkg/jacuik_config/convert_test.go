@@ -0,0 +1,168 @@
+package jacuik_config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const roundTripConfig = `# The demo project.
+version: 2
+name: demo
+description: "A project: with punctuation"
+vars:
+  port: 9000
+backend:
+  url: file://./state
+services:
+  # The public API.
+  - name: api
+    path: ./api
+    public: true
+    port: ${var:port}
+    dependsOn: [db, jobs]
+    env:
+      LOG_LEVEL: debug
+      RETRIES: "3"
+  - name: worker # processes jobs
+    path: ./api
+    kind: worker
+    dependsOn: [jobs]
+resources:
+  - name: db
+    type: postgres
+  - name: jobs
+    type: queue
+`
+
+// convertFile converts file to typ and reads the result back like it would
+// be read from disk.
+func convertFile(t *testing.T, file *ConfigFile, typ string) *ConfigFile {
+	t.Helper()
+
+	converted, err := file.Convert(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := converted.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if validationErrors := ValidateConfig(converted.Path, contents); len(validationErrors) > 0 {
+		t.Fatalf("%s\n%s", validationErrors, contents)
+	}
+
+	parsed, err := parseConfigFile(converted.Path, typ, contents)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, contents)
+	}
+
+	return parsed
+}
+
+func decodeFile(t *testing.T, file *ConfigFile) *AppConfig {
+	t.Helper()
+
+	config, err := file.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file and JSON Schema pointer are expected to differ.
+	config.file = nil
+	config.Schema = ""
+	for i := range config.Services {
+		config.Services[i].file = nil
+	}
+	for i := range config.Resources {
+		config.Resources[i].file = nil
+	}
+
+	return config
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	paths := [][]string{
+		{"yaml", "toml", "yaml"},
+		{"yaml", "json", "yaml"},
+		{"yaml", "toml", "json", "toml"},
+		{"yaml", "json", "toml", "json", "yaml"},
+	}
+
+	for _, path := range paths {
+		t.Run(strings.Join(path, "-"), func(t *testing.T) {
+			dir := writeProject(t, map[string]string{
+				"schema.yaml":    roundTripConfig,
+				"api/Dockerfile": "FROM scratch\n",
+			})
+
+			file, err := LoadConfigFile(filepath.Join(dir, "schema.yaml"), "yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := decodeFile(t, file)
+
+			for _, typ := range path[1:] {
+				file = convertFile(t, file, typ)
+
+				if got := decodeFile(t, file); !reflect.DeepEqual(got, want) {
+					t.Fatalf("the config changed converting to %s:\ngot  %+v\nwant %+v", typ, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertKeepsComments(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"schema.yaml":    roundTripConfig,
+		"api/Dockerfile": "FROM scratch\n",
+	})
+
+	file, err := LoadConfigFile(filepath.Join(dir, "schema.yaml"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toml := convertFile(t, file, "toml")
+	contents, err := toml.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"#:schema ./.jacuik/jacuik.schema.json\n",
+		"# The demo project.\n",
+		"# The public API.\n",
+		"# processes jobs\n",
+		"port = \"${var:port}\"\n",
+	} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("the TOML file is missing %q:\n%s", want, contents)
+		}
+	}
+
+	yaml := convertFile(t, toml, "yaml")
+	contents, err = yaml.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"# yaml-language-server: $schema=./.jacuik/jacuik.schema.json\n",
+		"# The demo project.\n",
+		"# The public API.\n",
+		"# processes jobs\n",
+	} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("the YAML file is missing %q:\n%s", want, contents)
+		}
+	}
+
+	if strings.Contains(string(contents), "#:schema") {
+		t.Errorf("the YAML file still points at the schema like TOML:\n%s", contents)
+	}
+}
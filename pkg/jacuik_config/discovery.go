@@ -0,0 +1,106 @@
+package jacuik_config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configFileNames are the names the config file may have.
+var configFileNames = []string{"schema.yaml", "schema.yml", "schema.json", "schema.toml"}
+
+// configFileOverride is the config file supplied with the --config flag.
+var configFileOverride string
+
+// SetConfigFile makes the config be read from filePath instead of being
+// searched for from the working directory.
+func SetConfigFile(filePath string) {
+	configFileOverride = filePath
+}
+
+// LocateConfigFile returns the path and type of the config file set with
+// SetConfigFile or, if none was set, found from the working directory.
+func LocateConfigFile() (string, string, error) {
+	if configFileOverride != "" {
+		filePath, err := filepath.Abs(configFileOverride)
+		if err != nil {
+			return "", "", err
+		}
+
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return "", "", fmt.Errorf("The config file [%s] could not be read: %w", configFileOverride, err)
+		}
+		if info.IsDir() {
+			return "", "", fmt.Errorf("The config file [%s] is a directory.", configFileOverride)
+		}
+
+		configType, err := configTypeFromPath(filePath)
+		if err != nil {
+			return "", "", err
+		}
+
+		return filePath, configType, nil
+	}
+
+	currentDirectory, err := os.Getwd()
+	if err != nil {
+		return "", "", err
+	}
+
+	return FindConfigFile(currentDirectory)
+}
+
+// FindConfigFile searches dirPath, and then each of its parents, for the
+// config file and returns its path and type. Having more than one config
+// file in the same directory is an error.
+func FindConfigFile(dirPath string) (string, string, error) {
+	dirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	for {
+		var found []string
+		for _, name := range configFileNames {
+			info, err := os.Stat(filepath.Join(dirPath, name))
+			if err == nil && !info.IsDir() {
+				found = append(found, name)
+			}
+		}
+
+		if len(found) > 1 {
+			return "", "", fmt.Errorf("Found multiple schema files in %s: %s. Please keep only one.", dirPath, strings.Join(found, ", "))
+		}
+
+		if len(found) == 1 {
+			filePath := filepath.Join(dirPath, found[0])
+			configType, err := configTypeFromPath(filePath)
+			if err != nil {
+				return "", "", err
+			}
+
+			return filePath, configType, nil
+		}
+
+		parent := filepath.Dir(dirPath)
+		if parent == dirPath {
+			return "", "", fmt.Errorf("The schema file was not found. Please ensure you are in a Jacuik project or pass --config.")
+		}
+		dirPath = parent
+	}
+}
+
+func configTypeFromPath(filePath string) (string, error) {
+	switch filepath.Ext(filePath) {
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".json":
+		return "json", nil
+	case ".toml":
+		return "toml", nil
+	default:
+		return "", fmt.Errorf("Unknown config file type [%s], expected a .yaml, .yml, .json or .toml file.", filepath.Base(filePath))
+	}
+}
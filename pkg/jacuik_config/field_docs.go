@@ -0,0 +1,82 @@
+package jacuik_config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldDoc describes a single field of the config file.
+type FieldDoc struct {
+	Name        string
+	Type        string
+	Description string
+	Default     string
+	Enum        []string
+	Required    bool
+}
+
+// LookupFieldDoc returns the documentation for the field at path, for
+// example ["services", "port"]. List indexes are not part of the path.
+func LookupFieldDoc(path []string) (FieldDoc, bool) {
+	typ := reflect.TypeOf(AppConfig{})
+
+	var field reflect.StructField
+	for i, name := range path {
+		for typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+
+		if typ.Kind() != reflect.Struct {
+			return FieldDoc{}, false
+		}
+
+		var ok bool
+		field, ok = yamlFieldNames(typ)[name]
+		if !ok {
+			return FieldDoc{}, false
+		}
+
+		if i < len(path)-1 {
+			typ = field.Type
+		}
+	}
+
+	if len(path) == 0 {
+		return FieldDoc{}, false
+	}
+
+	options := parseJSONSchemaTag(field.Tag.Get("jsonschema"))
+	doc := FieldDoc{
+		Name:        path[len(path)-1],
+		Type:        fieldTypeName(field.Type),
+		Description: field.Tag.Get("description"),
+		Default:     options["default"],
+	}
+
+	if enum, ok := options["enum"]; ok {
+		doc.Enum = strings.Split(enum, "|")
+	}
+
+	_, doc.Required = options["required"]
+
+	return doc, true
+}
+
+func fieldTypeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return fieldTypeName(typ.Elem())
+	case reflect.Slice:
+		return "list of " + fieldTypeName(typ.Elem())
+	case reflect.Map:
+		return "mapping of " + fieldTypeName(typ.Elem())
+	case reflect.Struct:
+		return "mapping"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int:
+		return "integer"
+	default:
+		return "string"
+	}
+}
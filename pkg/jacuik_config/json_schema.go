@@ -0,0 +1,178 @@
+package jacuik_config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// JSONSchemaFilePath is where the JSON Schema is written, relative to the
+	// config file, so editors can find it.
+	JSONSchemaFilePath = "./.jacuik/jacuik.schema.json"
+
+	jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+)
+
+// JSONSchema returns the JSON Schema describing the config file.
+func JSONSchema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(AppConfig{}))
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "Jacuik project config"
+
+	// The newest version is the one this CLI writes.
+	properties := schema["properties"].(map[string]interface{})
+	properties["version"].(map[string]interface{})["maximum"] = CurrentConfigVersion
+
+	return schema
+}
+
+// MarshalJSONSchema returns the JSON Schema as indented JSON.
+func MarshalJSONSchema() ([]byte, error) {
+	contents, err := json.MarshalIndent(JSONSchema(), "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(contents, '\n'), nil
+}
+
+// WriteJSONSchemaFile writes the JSON Schema to filePath, creating any
+// missing directories.
+func WriteJSONSchemaFile(filePath string) error {
+	contents, err := MarshalJSONSchema()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, contents, 0644)
+}
+
+func typeSchema(typ reflect.Type) map[string]interface{} {
+	switch typ.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+
+			property := typeSchema(field.Type)
+			if description := field.Tag.Get("description"); description != "" {
+				property["description"] = description
+			}
+
+			options := parseJSONSchemaTag(field.Tag.Get("jsonschema"))
+			if _, ok := options["required"]; ok {
+				required = append(required, name)
+			}
+			if _, ok := options["name"]; ok {
+				property["pattern"] = namePattern.String()
+				property["maxLength"] = maxNameLength
+			}
+			if enum, ok := options["enum"]; ok {
+				property["enum"] = strings.Split(enum, "|")
+			}
+			if value, ok := options["default"]; ok {
+				property["default"] = schemaValue(field.Type, value)
+			}
+			if value, ok := options["minimum"]; ok {
+				property["minimum"] = schemaValue(field.Type, value)
+			}
+			if value, ok := options["maximum"]; ok {
+				property["maximum"] = schemaValue(field.Type, value)
+			}
+
+			properties[name] = property
+		}
+
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+
+		return schema
+	case reflect.Ptr:
+		// Optional fields are pointers so a missing value can be told
+		// apart from the zero value.
+		return typeSchema(typ.Elem())
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(typ.Elem()),
+		}
+	case reflect.Map:
+		// Vars may be written as any scalar, they are read as strings.
+		return map[string]interface{}{
+			"type": "object",
+			"additionalProperties": map[string]interface{}{
+				"type": []string{"string", "number", "boolean"},
+			},
+		}
+	case reflect.Bool:
+		return interpolatedSchema("boolean")
+	case reflect.Int:
+		return interpolatedSchema("integer")
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// interpolatedSchema allows a value of the given type or a single ${...}
+// expression that resolves to one.
+func interpolatedSchema(typ string) map[string]interface{} {
+	return map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": typ},
+			map[string]interface{}{"type": "string", "pattern": `^\$\{[^}]+\}$`},
+		},
+	}
+}
+
+// parseJSONSchemaTag parses a tag like `required,enum=a|b,default=a`.
+func parseJSONSchemaTag(tag string) map[string]string {
+	result := make(map[string]string)
+	if tag == "" {
+		return result
+	}
+
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		result[key] = value
+	}
+
+	return result
+}
+
+func schemaValue(typ reflect.Type, value string) interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		result, _ := strconv.ParseBool(value)
+		return result
+	case reflect.Int:
+		result, _ := strconv.Atoi(value)
+		return result
+	default:
+		return value
+	}
+}
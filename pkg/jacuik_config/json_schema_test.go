@@ -0,0 +1,44 @@
+package jacuik_config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONSchemaOptionalFields(t *testing.T) {
+	contents, err := MarshalJSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Properties struct {
+			Network struct {
+				Properties map[string]struct {
+					AnyOf []struct {
+						Type string `json:"type"`
+					} `json:"anyOf"`
+					Type string `json:"type"`
+				} `json:"properties"`
+			} `json:"network"`
+		} `json:"properties"`
+	}
+	err = json.Unmarshal(contents, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assignPublicIp is a *bool so that it can be left out.
+	assignPublicIP, ok := schema.Properties.Network.Properties["assignPublicIp"]
+	if !ok {
+		t.Fatal("the network has no assignPublicIp property")
+	}
+	if assignPublicIP.Type != "" || len(assignPublicIP.AnyOf) == 0 || assignPublicIP.AnyOf[0].Type != "boolean" {
+		t.Errorf("assignPublicIp is %+v in the schema, expected a boolean or an expression", assignPublicIP)
+	}
+
+	fieldDoc, ok := LookupFieldDoc([]string{"network", "assignPublicIp"})
+	if !ok || fieldDoc.Type != "boolean" {
+		t.Errorf("the docs of assignPublicIp have the type %q, expected boolean", fieldDoc.Type)
+	}
+}
@@ -0,0 +1,128 @@
+package jacuik_config
+
+import (
+	"fmt"
+	"strconv"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// CurrentConfigVersion is the version of the config file written by this
+// version of the CLI. Files without a version are version 1.
+const CurrentConfigVersion = 2
+
+// Migration upgrades a config document from one version to the next. The
+// document is edited in place, the version field is updated by the caller.
+type Migration struct {
+	From        int
+	Description string
+	Migrate     func(root *yaml.Node) error
+}
+
+// migrations must be kept in order, each one upgrades From to From+1.
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "Add the version field.",
+		Migrate: func(root *yaml.Node) error {
+			// Version 2 only introduced the version field itself.
+			return nil
+		},
+	},
+}
+
+// DocumentVersion returns the version of a config document.
+func DocumentVersion(root *yaml.Node) (int, *yaml.Node, error) {
+	versionNode := mappingValue(root, "version")
+	if versionNode == nil {
+		return 1, nil, nil
+	}
+
+	version, err := strconv.Atoi(versionNode.Value)
+	if err != nil || versionNode.Kind != yaml.ScalarNode {
+		return 0, versionNode, fmt.Errorf("the version must be a whole number")
+	}
+
+	if version < 1 {
+		return 0, versionNode, fmt.Errorf("the version must be at least 1")
+	}
+
+	if version > CurrentConfigVersion {
+		return 0, versionNode, fmt.Errorf("version %d is newer than this CLI supports (%d), please upgrade jacuik", version, CurrentConfigVersion)
+	}
+
+	return version, versionNode, nil
+}
+
+// MigrateDocument upgrades a config document to CurrentConfigVersion one
+// version at a time and returns the migrations that were applied.
+func MigrateDocument(root *yaml.Node) ([]Migration, error) {
+	version, _, err := DocumentVersion(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range migrations {
+		if migration.From < version {
+			continue
+		}
+
+		err = migration.Migrate(root)
+		if err != nil {
+			return applied, fmt.Errorf("Couldn't migrate the config from version %d to %d: %w", migration.From, migration.From+1, err)
+		}
+
+		version = migration.From + 1
+		setDocumentVersion(root, version)
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+func setDocumentVersion(root *yaml.Node, version int) {
+	if versionNode := mappingValue(root, "version"); versionNode != nil {
+		versionNode.Kind = yaml.ScalarNode
+		versionNode.Tag = "!!int"
+		versionNode.Value = strconv.Itoa(version)
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(root.Content) > 0 && root.Content[0].Style&yaml.DoubleQuotedStyle != 0 {
+		key.Style = yaml.DoubleQuotedStyle
+	}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+
+	// Put the version first, after $schema if the file has one.
+	index := 0
+	if len(root.Content) > 1 && root.Content[0].Value == "$schema" {
+		index = 2
+	}
+
+	// Keep comments at the top of the file above the version.
+	if index == 0 && len(root.Content) > 0 {
+		key.HeadComment = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+
+	content := append([]*yaml.Node{}, root.Content[:index]...)
+	content = append(content, key, value)
+	root.Content = append(content, root.Content[index:]...)
+}
+
+// mappingValue returns the value of key in a mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
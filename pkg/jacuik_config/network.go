@@ -0,0 +1,51 @@
+package jacuik_config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NetworkConfig adopts existing networking instead of creating it for the
+// project. Everything left out is created as before.
+type NetworkConfig struct {
+	VpcID           string   `yaml:"vpcId,omitempty" json:"vpcId,omitempty" description:"The ID of an existing VPC to run the services in, like vpc-0123456789abcdef0. Without it a new VPC is created."`
+	SubnetIDs       []string `yaml:"subnetIds,omitempty" json:"subnetIds,omitempty" description:"The subnets of vpcId the services, and a new load balancer, run in. Required with vpcId unless loadBalancerArn is set, then it defaults to every subnet of the VPC."`
+	AssignPublicIP  *bool    `yaml:"assignPublicIp,omitempty" json:"assignPublicIp,omitempty" description:"Whether the services get a public IP. They need one, or a NAT gateway, to pull their images. Defaults to true in a new VPC and false in an existing one."`
+	Cluster         string   `yaml:"cluster,omitempty" json:"cluster,omitempty" description:"The name or ARN of an existing ECS cluster to run the services in. Without it a new cluster is created."`
+	LoadBalancerArn string   `yaml:"loadBalancerArn,omitempty" json:"loadBalancerArn,omitempty" description:"The ARN of an existing application load balancer in vpcId to route traffic through. Without it a new load balancer is created."`
+	ListenerArn     string   `yaml:"listenerArn,omitempty" json:"listenerArn,omitempty" description:"The listener of loadBalancerArn that forwards to the services. A rule is added after its existing rules, so they keep their traffic."`
+}
+
+// GetAssignPublicIP returns whether the services get a public IP.
+func (n NetworkConfig) GetAssignPublicIP() bool {
+	if n.AssignPublicIP == nil {
+		return n.VpcID == ""
+	}
+
+	return *n.AssignPublicIP
+}
+
+// GetClusterName returns the name of the adopted cluster, which may have
+// been given as an ARN.
+func (n NetworkConfig) GetClusterName() string {
+	if strings.HasPrefix(n.Cluster, "arn:") {
+		return n.Cluster[strings.LastIndex(n.Cluster, "/")+1:]
+	}
+
+	return n.Cluster
+}
+
+var (
+	vpcIDPattern           = regexp.MustCompile(`^vpc-[0-9a-f]+$`)
+	subnetIDPattern        = regexp.MustCompile(`^subnet-[0-9a-f]+$`)
+	clusterPattern         = regexp.MustCompile(`^([A-Za-z0-9_-]{1,255}|arn:aws[a-z-]*:ecs:[a-z0-9-]+:[0-9]{12}:cluster/[A-Za-z0-9_-]{1,255})$`)
+	loadBalancerArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:elasticloadbalancing:[a-z0-9-]+:[0-9]{12}:loadbalancer/app/[^/]+/[0-9a-f]+$`)
+	listenerArnPattern     = regexp.MustCompile(`^arn:aws[a-z-]*:elasticloadbalancing:[a-z0-9-]+:[0-9]{12}:listener/app/[^/]+/[0-9a-f]+/[0-9a-f]+$`)
+)
+
+// listenerBelongsTo reports whether the listener ARN is a listener of the
+// load balancer ARN.
+func listenerBelongsTo(listenerArn, loadBalancerArn string) bool {
+	loadBalancer := strings.Replace(loadBalancerArn, ":loadbalancer/", ":listener/", 1)
+	return strings.HasPrefix(listenerArn, loadBalancer+"/")
+}
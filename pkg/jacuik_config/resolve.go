@@ -0,0 +1,472 @@
+package jacuik_config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// interpolationPattern matches ${provider:key} expressions. $${...} escapes an
+// expression so it is kept as is, without the first $.
+var interpolationPattern = regexp.MustCompile(`\$?\$\{([^}:]*):([^}]*)\}`)
+
+// InterpolationProvider resolves the key of a ${provider:key} expression.
+type InterpolationProvider func(projectDirectory, key string) (string, error)
+
+var interpolationProviders = map[string]InterpolationProvider{
+	"env": interpolateEnv,
+	"git": interpolateGit,
+}
+
+// lazyInterpolationProviders are resolved only when a provider has been
+// registered for them, otherwise their expressions are left as is. This
+// lets commands like validate work without touching the cloud.
+var lazyInterpolationProviders = map[string]bool{
+	"stack": true,
+}
+
+// RegisterInterpolationProvider makes ${name:key} expressions in the config
+// resolve through provider.
+func RegisterInterpolationProvider(name string, provider InterpolationProvider) {
+	interpolationProviders[name] = provider
+}
+
+// LeaveUnresolved keeps ${name:key} expressions as they are instead of
+// resolving them, for commands that write the config somewhere the values
+// shouldn't end up, like secrets from the environment.
+func LeaveUnresolved(name string) {
+	delete(interpolationProviders, name)
+	lazyInterpolationProviders[name] = true
+}
+
+func interpolateEnv(projectDirectory, key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("the environment variable %s is not set", key)
+	}
+
+	return value, nil
+}
+
+func interpolateGit(projectDirectory, key string) (string, error) {
+	var args []string
+	switch key {
+	case "sha":
+		args = []string{"rev-parse", "HEAD"}
+	case "short-sha":
+		args = []string{"rev-parse", "--short", "HEAD"}
+	case "branch":
+		args = []string{"rev-parse", "--abbrev-ref", "HEAD"}
+	default:
+		return "", fmt.Errorf("unknown git value %q, expected sha, short-sha or branch", key)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = projectDirectory
+	output, err := cmd.Output()
+	if err != nil {
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) && len(exitError.Stderr) > 0 {
+			message := strings.SplitN(strings.TrimSpace(string(exitError.Stderr)), "\n", 2)[0]
+			return "", fmt.Errorf("couldn't read git %s: %s", key, message)
+		}
+		return "", fmt.Errorf("couldn't read git %s: %w", key, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// includedConfig is the part of the config an included file may contain.
+// Service paths in included files are relative to the project directory.
+type includedConfig struct {
+	Include   []string         `yaml:"include,omitempty"`
+	Services  []ServiceConfig  `yaml:"services,omitempty"`
+	Resources []ResourceConfig `yaml:"resources,omitempty"`
+}
+
+// resolvedConfig is a copy of a config document with its includes merged
+// in and its interpolations resolved.
+type resolvedConfig struct {
+	root *yaml.Node
+	// nodeFiles maps every node that came from an included file to the
+	// name of that file, for error messages.
+	nodeFiles map[*yaml.Node]string
+	// serviceFiles and resourceFiles hold the file each merged service and
+	// resource was declared in, in order.
+	serviceFiles  []*ConfigFile
+	resourceFiles []*ConfigFile
+	errors        ValidationErrors
+}
+
+type resolver struct {
+	file             *ConfigFile
+	projectDirectory string
+	result           *resolvedConfig
+
+	// loaded holds included files that were already loaded, by path.
+	loaded map[string]*ConfigFile
+
+	vars         map[string]*yaml.Node
+	resolvedVars map[string]string
+	// resolvingVars holds the vars currently being resolved to catch
+	// vars that reference themselves.
+	resolvingVars []string
+
+	// excludedProviders can't be used in the values being resolved, with
+	// the reason why.
+	excludedProviders map[string]string
+}
+
+func newResolver(file *ConfigFile) *resolver {
+	r := &resolver{
+		file:             file,
+		projectDirectory: filepath.Dir(file.Path),
+		loaded:           make(map[string]*ConfigFile),
+		vars:             make(map[string]*yaml.Node),
+		resolvedVars:     make(map[string]string),
+	}
+
+	if vars := mappingValue(file.document.Content[0], "vars"); vars != nil && vars.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(vars.Content); i += 2 {
+			r.vars[vars.Content[i].Value] = vars.Content[i+1]
+		}
+	}
+
+	return r
+}
+
+// resolve merges the includes of the file and resolves its interpolations.
+// The file's own document is left untouched.
+func (c *ConfigFile) resolve() *resolvedConfig {
+	r := newResolver(c)
+	r.result = &resolvedConfig{
+		root:      copyNode(c.document.Content[0]),
+		nodeFiles: make(map[*yaml.Node]string),
+	}
+
+	root := r.result.root
+	r.resolveNode(root, r.displayName(c.Path))
+
+	for range sequenceItems(mappingValue(root, "services")) {
+		r.result.serviceFiles = append(r.result.serviceFiles, c)
+	}
+	for range sequenceItems(mappingValue(root, "resources")) {
+		r.result.resourceFiles = append(r.result.resourceFiles, c)
+	}
+
+	// Reuse included files that are already loaded so changes made to them
+	// in memory are seen.
+	for _, included := range c.includes {
+		r.loaded[included.Path] = included
+	}
+	c.includes = nil
+	r.mergeIncludes(c, root, []string{c.Path})
+
+	return r.result
+}
+
+// interpolate resolves every expression in value.
+func (r *resolver) interpolate(value string) (string, error) {
+	var firstErr error
+	result := interpolationPattern.ReplaceAllStringFunc(value, func(expression string) string {
+		if strings.HasPrefix(expression, "$$") {
+			return expression[1:]
+		}
+
+		match := interpolationPattern.FindStringSubmatch(expression)
+		provider, key := match[1], match[2]
+
+		resolved, err := r.interpolateExpression(provider, key)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("couldn't resolve %s: %w", expression, err)
+			}
+			return expression
+		}
+
+		return resolved
+	})
+
+	return result, firstErr
+}
+
+func (r *resolver) interpolateExpression(provider, key string) (string, error) {
+	if provider == "var" {
+		return r.resolveVar(key)
+	}
+
+	if reason, ok := r.excludedProviders[provider]; ok {
+		return "", errors.New(reason)
+	}
+
+	interpolationProvider, ok := interpolationProviders[provider]
+	if !ok {
+		if lazyInterpolationProviders[provider] {
+			return fmt.Sprintf("${%s:%s}", provider, key), nil
+		}
+
+		return "", fmt.Errorf("unknown provider %q, expected env, git, stack or var", provider)
+	}
+
+	return interpolationProvider(r.projectDirectory, key)
+}
+
+func (r *resolver) resolveVar(name string) (string, error) {
+	if value, ok := r.resolvedVars[name]; ok {
+		return value, nil
+	}
+
+	node, ok := r.vars[name]
+	if !ok {
+		return "", fmt.Errorf("the var %q is not declared in vars", name)
+	}
+
+	for i, resolving := range r.resolvingVars {
+		if resolving == name {
+			cycle := append(append([]string{}, r.resolvingVars[i:]...), name)
+			return "", fmt.Errorf("vars reference each other in a cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	r.resolvingVars = append(r.resolvingVars, name)
+	defer func() {
+		r.resolvingVars = r.resolvingVars[:len(r.resolvingVars)-1]
+	}()
+
+	value, err := r.interpolate(node.Value)
+	if err != nil {
+		return "", err
+	}
+
+	r.resolvedVars[name] = value
+	return value, nil
+}
+
+// resolveNode resolves the interpolations in every scalar value under node.
+func (r *resolver) resolveNode(node *yaml.Node, fileName string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		// Keys are never interpolated.
+		for i := 1; i < len(node.Content); i += 2 {
+			r.resolveNode(node.Content[i], fileName)
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			r.resolveNode(child, fileName)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+
+		value, err := r.interpolate(node.Value)
+		if err != nil {
+			r.addError(node, fileName, err.Error())
+			return
+		}
+
+		// A value that is a single expression takes the type of what it
+		// resolves to, so `port: ${var:port}` is still a number.
+		if interpolationPattern.FindString(node.Value) == node.Value && !strings.HasPrefix(node.Value, "$$") {
+			node.Tag = ""
+			node.Style = 0
+		}
+
+		node.Value = value
+	}
+}
+
+// mergeIncludes loads the files included by file and appends their services
+// and resources to root. stack holds the files being included to detect
+// cycles.
+func (r *resolver) mergeIncludes(file *ConfigFile, root *yaml.Node, stack []string) {
+	includeNode := mappingValue(file.document.Content[0], "include")
+	if includeNode == nil || includeNode.Kind != yaml.SequenceNode {
+		return
+	}
+
+	fileName := r.displayName(file.Path)
+	for _, item := range includeNode.Content {
+		if item.Kind != yaml.ScalarNode {
+			continue
+		}
+
+		includePath, err := r.interpolate(item.Value)
+		if err != nil {
+			r.addError(item, fileName, err.Error())
+			continue
+		}
+
+		// Includes are relative to the file that includes them.
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(file.Path), includePath)
+		}
+		includePath = filepath.Clean(includePath)
+
+		if cycleStart := indexOf(stack, includePath); cycleStart != -1 {
+			var cycle []string
+			for _, path := range append(stack[cycleStart:], includePath) {
+				cycle = append(cycle, r.displayName(path))
+			}
+			r.addError(item, fileName, fmt.Sprintf("include cycle: %s", strings.Join(cycle, " -> ")))
+			continue
+		}
+
+		if r.isIncluded(includePath) {
+			r.addError(item, fileName, fmt.Sprintf("%s is included more than once", r.displayName(includePath)))
+			continue
+		}
+
+		configType, err := configTypeFromPath(includePath)
+		if err != nil {
+			r.addError(item, fileName, err.Error())
+			continue
+		}
+
+		included, ok := r.loaded[includePath]
+		if !ok {
+			included, err = LoadConfigFile(includePath, configType)
+			if err != nil {
+				r.addError(item, fileName, fmt.Sprintf("couldn't read included file: %s", err))
+				continue
+			}
+		}
+		r.file.includes = append(r.file.includes, included)
+
+		r.mergeInclude(included, root, append(stack, includePath))
+	}
+}
+
+func (r *resolver) mergeInclude(included *ConfigFile, root *yaml.Node, stack []string) {
+	includedName := r.displayName(included.Path)
+	includedRoot := included.document.Content[0]
+	if includedRoot.Kind != yaml.MappingNode {
+		r.addError(includedRoot, includedName, "an included file must be a mapping")
+		return
+	}
+
+	for i := 0; i+1 < len(includedRoot.Content); i += 2 {
+		key := includedRoot.Content[i]
+		switch key.Value {
+		case "include", "services", "resources":
+		default:
+			r.addError(key, includedName, fmt.Sprintf("unknown field %q in an included file, only include, services and resources are allowed", key.Value))
+		}
+	}
+
+	for _, listName := range []string{"services", "resources"} {
+		list := mappingValue(includedRoot, listName)
+		if list == nil {
+			continue
+		}
+
+		copied := copyNode(list)
+		r.markFile(copied, includedName)
+		r.resolveNode(copied, includedName)
+
+		target := mappingValue(root, listName)
+		if target == nil || target.Kind != yaml.SequenceNode {
+			if target != nil {
+				// The root list is invalid, the validator reports it.
+				continue
+			}
+
+			target = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: listName}, target)
+		}
+
+		if copied.Kind != yaml.SequenceNode {
+			r.addError(copied, includedName, fmt.Sprintf("%s must be a list", listName))
+			continue
+		}
+
+		target.Content = append(target.Content, copied.Content...)
+		for range copied.Content {
+			if listName == "services" {
+				r.result.serviceFiles = append(r.result.serviceFiles, included)
+			} else {
+				r.result.resourceFiles = append(r.result.resourceFiles, included)
+			}
+		}
+	}
+
+	r.mergeIncludes(included, root, stack)
+}
+
+func (r *resolver) isIncluded(path string) bool {
+	for _, included := range r.file.includes {
+		if included.Path == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *resolver) markFile(node *yaml.Node, fileName string) {
+	r.result.nodeFiles[node] = fileName
+	for _, child := range node.Content {
+		r.markFile(child, fileName)
+	}
+}
+
+func (r *resolver) addError(node *yaml.Node, fileName, message string) {
+	r.result.errors = append(r.result.errors, ValidationError{
+		File:    fileName,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: message,
+	})
+}
+
+// displayName returns the path of a file relative to the project directory.
+func (r *resolver) displayName(path string) string {
+	relative, err := filepath.Rel(r.projectDirectory, path)
+	if err != nil {
+		return path
+	}
+
+	return relative
+}
+
+func copyNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	copied := *node
+	copied.Content = nil
+	for _, child := range node.Content {
+		copied.Content = append(copied.Content, copyNode(child))
+	}
+
+	if node.Alias != nil {
+		copied.Alias = copyNode(node.Alias)
+	}
+
+	return &copied
+}
+
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	return node.Content
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,338 @@
+package jacuik_config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// services are the names of the services after the includes are
+		// merged, when the config is valid.
+		services []string
+		errors   []string
+	}{
+		{
+			name: "merged in order",
+			files: map[string]string{
+				"schema.yaml": `version: 2
+name: demo
+include:
+  - ./web.yaml
+services:
+  - name: api
+    path: ./api
+`,
+				"web.yaml": `include:
+  - ./nested/workers.yaml
+services:
+  - name: web
+    path: ./api
+    dependsOn: [worker]
+`,
+				"nested/workers.yaml": `services:
+  - name: worker
+    path: ./api
+    kind: worker
+`,
+			},
+			services: []string{"api", "web", "worker"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"schema.yaml": `version: 2
+name: demo
+include: [./a.yaml]
+`,
+				"a.yaml": `include: [./b.yaml]
+`,
+				"b.yaml": `include: [./a.yaml]
+`,
+			},
+			errors: []string{"b.yaml:1:11: include cycle: a.yaml -> b.yaml -> a.yaml"},
+		},
+		{
+			name: "includes the root file",
+			files: map[string]string{
+				"schema.yaml": `version: 2
+name: demo
+include: [./a.yaml]
+`,
+				"a.yaml": `include: [./schema.yaml]
+`,
+			},
+			errors: []string{"a.yaml:1:11: include cycle: schema.yaml -> a.yaml -> schema.yaml"},
+		},
+		{
+			name: "included twice",
+			files: map[string]string{
+				"schema.yaml": `version: 2
+name: demo
+include: [./a.yaml, ./b.yaml]
+`,
+				"a.yaml": `include: [./b.yaml]
+`,
+				"b.yaml": `services: []
+`,
+			},
+			errors: []string{"schema.yaml:3:21: b.yaml is included more than once"},
+		},
+		{
+			name: "duplicate across files",
+			files: map[string]string{
+				"schema.yaml": `version: 2
+name: demo
+include: [./a.yaml]
+services:
+  - name: api
+    path: ./api
+`,
+				"a.yaml": `services:
+  - name: api
+    path: ./api
+`,
+			},
+			errors: []string{`a.yaml:2:11: "api" is already declared in schema.yaml on line 5`},
+		},
+		{
+			name: "project fields in an included file",
+			files: map[string]string{
+				"schema.yaml": `version: 2
+name: demo
+include: [./a.yaml]
+`,
+				"a.yaml": `name: other
+`,
+			},
+			errors: []string{`a.yaml:1:1: unknown field "name" in an included file, only include, services and resources are allowed`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := map[string]string{"api/Dockerfile": "FROM scratch\n"}
+			for name, contents := range test.files {
+				files[name] = contents
+			}
+			dir := writeProject(t, files)
+			filePath := filepath.Join(dir, "schema.yaml")
+
+			validationErrors, err := ValidateConfigFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, err := range validationErrors {
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.errors, "\n") {
+				t.Fatalf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.errors, "\n"))
+			}
+
+			if len(test.errors) > 0 {
+				return
+			}
+
+			file, err := LoadConfigFile(filePath, "yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := file.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var services []string
+			for _, svc := range config.Services {
+				services = append(services, svc.Name)
+			}
+			if !reflect.DeepEqual(services, test.services) {
+				t.Errorf("got services %v, want %v", services, test.services)
+			}
+		})
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	t.Setenv("JACUIK_TEST_DOMAIN", "example.com")
+
+	tests := []struct {
+		name   string
+		config string
+		// check is called with the decoded config when it is valid.
+		check  func(t *testing.T, config *AppConfig)
+		errors []string
+	}{
+		{
+			name: "vars and env",
+			config: `version: 2
+name: demo
+vars:
+  port: 9000
+  domain: ${env:JACUIK_TEST_DOMAIN}
+  host: api.${var:domain}
+services:
+  - name: api
+    path: ./api
+    port: ${var:port}
+    env:
+      HOST: ${var:host}
+      TEMPLATE: $${var:host}
+`,
+			check: func(t *testing.T, config *AppConfig) {
+				svc, _ := config.GetService("api")
+				if svc.Port != 9000 {
+					t.Errorf("got port %d, want 9000", svc.Port)
+				}
+				if svc.Env["HOST"] != "api.example.com" {
+					t.Errorf("got HOST %q, want %q", svc.Env["HOST"], "api.example.com")
+				}
+				if svc.Env["TEMPLATE"] != "${var:host}" {
+					t.Errorf("got TEMPLATE %q, want %q", svc.Env["TEMPLATE"], "${var:host}")
+				}
+			},
+		},
+		{
+			name: "stack outputs are left for later",
+			config: `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+    env:
+      URL: ${stack:url}
+`,
+			check: func(t *testing.T, config *AppConfig) {
+				svc, _ := config.GetService("api")
+				if svc.Env["URL"] != "${stack:url}" {
+					t.Errorf("got URL %q, want %q", svc.Env["URL"], "${stack:url}")
+				}
+			},
+		},
+		{
+			name: "var cycle",
+			config: `version: 2
+name: demo
+vars:
+  a: ${var:b}
+  b: ${var:a}
+`,
+			errors: []string{
+				`schema.yaml:4:6: couldn't resolve ${var:b}: couldn't resolve ${var:a}: couldn't resolve ${var:b}: vars reference each other in a cycle: b -> a -> b`,
+				`schema.yaml:5:6: couldn't resolve ${var:a}: couldn't resolve ${var:b}: couldn't resolve ${var:a}: vars reference each other in a cycle: a -> b -> a`,
+			},
+		},
+		{
+			name: "unresolvable",
+			config: `version: 2
+name: demo
+description: ${var:missing} ${env:JACUIK_TEST_MISSING} ${nope:x}
+`,
+			errors: []string{`schema.yaml:3:14: couldn't resolve ${var:missing}: the var "missing" is not declared in vars`},
+		},
+		{
+			name: "resolved value has the wrong type",
+			config: `version: 2
+name: demo
+vars:
+  port: http
+services:
+  - name: api
+    path: ./api
+    port: ${var:port}
+`,
+			errors: []string{`schema.yaml:8:11: the port of service "api" must be a whole number`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeProject(t, map[string]string{
+				"schema.yaml":    test.config,
+				"api/Dockerfile": "FROM scratch\n",
+			})
+			filePath := filepath.Join(dir, "schema.yaml")
+
+			validationErrors, err := ValidateConfigFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, err := range validationErrors {
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.errors, "\n") {
+				t.Fatalf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.errors, "\n"))
+			}
+
+			if len(test.errors) > 0 {
+				return
+			}
+
+			file, err := LoadConfigFile(filePath, "yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := file.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.check(t, config)
+		})
+	}
+}
+
+func TestLeaveUnresolved(t *testing.T) {
+	t.Setenv("JACUIK_TEST_KEY", "secret")
+
+	provider := interpolationProviders["env"]
+	t.Cleanup(func() {
+		interpolationProviders["env"] = provider
+		delete(lazyInterpolationProviders, "env")
+	})
+	LeaveUnresolved("env")
+
+	dir := writeProject(t, map[string]string{
+		"schema.yaml": `version: 2
+name: demo
+vars:
+  key: ${env:JACUIK_TEST_KEY}
+services:
+  - name: api
+    path: ./api
+    env:
+      KEY: ${var:key}
+      MISSING: Bearer ${env:JACUIK_TEST_MISSING}
+`,
+		"api/Dockerfile": "FROM scratch\n",
+	})
+
+	file, err := LoadConfigFile(filepath.Join(dir, "schema.yaml"), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := file.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, _ := config.GetService("api")
+	want := map[string]string{
+		"KEY":     "${env:JACUIK_TEST_KEY}",
+		"MISSING": "Bearer ${env:JACUIK_TEST_MISSING}",
+	}
+	if !reflect.DeepEqual(svc.Env, want) {
+		t.Errorf("got env %v, want %v", svc.Env, want)
+	}
+}
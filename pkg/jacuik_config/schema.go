@@ -1,30 +1,237 @@
 package jacuik_config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/zchase/jacuik/pkg/utils"
+	yaml "gopkg.in/yaml.v3"
 )
 
+// DefaultServicePort is the port a service listens on when none is configured.
+const DefaultServicePort = 80
+
+const (
+	// ServiceKindWeb is a service that serves HTTP traffic.
+	ServiceKindWeb = "web"
+	// ServiceKindWorker is a service that runs in the background and
+	// does not receive traffic.
+	ServiceKindWorker = "worker"
+)
+
+const (
+	ResourceTypePostgres = "postgres"
+	ResourceTypeRedis    = "redis"
+	ResourceTypeQueue    = "queue"
+)
+
+// ProviderECS deploys the project to AWS ECS on Fargate. It is the
+// default provider.
+const ProviderECS = "ecs"
+
+// providerNames are the providers a project can be deployed with. More are
+// added with RegisterProviderName.
+var providerNames = []string{ProviderECS}
+
+// RegisterProviderName makes name a valid value for provider in the config.
+func RegisterProviderName(name string) {
+	for _, existing := range providerNames {
+		if existing == name {
+			return
+		}
+	}
+
+	providerNames = append(providerNames, name)
+}
+
+// ProviderNames returns the providers a project can be deployed with.
+func ProviderNames() []string {
+	return append([]string(nil), providerNames...)
+}
+
+// The description and jsonschema tags on the config types are used to
+// generate the JSON Schema for the config file.
+
 type ServiceConfig struct {
-	Name             string `yaml:"name" json:"name"`
-	PathToDockerfile string `yaml:"path" json:"path"`
-	Public           bool   `yaml:"public" json:"public"`
+	Name             string            `yaml:"name" json:"name" jsonschema:"required,name" description:"The name of the service. Cloud resources for the service are named after it."`
+	Kind             string            `yaml:"kind,omitempty" json:"kind,omitempty" jsonschema:"enum=web|worker,default=web" description:"The kind of service. Web services receive HTTP traffic, workers run in the background."`
+	PathToDockerfile string            `yaml:"path" json:"path" jsonschema:"required" description:"The path, relative to the config file, of the directory containing the service's Dockerfile."`
+	Public           bool              `yaml:"public" json:"public" jsonschema:"default=false" description:"Whether the service is reachable from the internet through the load balancer."`
+	Port             int               `yaml:"port,omitempty" json:"port,omitempty" jsonschema:"default=80,minimum=1,maximum=65535" description:"The port the service listens on."`
+	DependsOn        []string          `yaml:"dependsOn,omitempty" json:"dependsOn,omitempty" description:"The names of the services and resources this service depends on."`
+	Env              map[string]string `yaml:"env,omitempty" json:"env,omitempty" description:"Environment variables set in the service's container."`
+
+	// file is the config file the service is declared in, if any.
+	file *ConfigFile
+}
+
+// ResourceConfig is a backing resource, like a database or a queue, that
+// services in the project depend on.
+type ResourceConfig struct {
+	Name string `yaml:"name" json:"name" jsonschema:"required,name" description:"The name of the resource. Services reference it in dependsOn."`
+	Type string `yaml:"type" json:"type" jsonschema:"required,enum=postgres|redis|queue" description:"The type of the resource."`
+
+	// file is the config file the resource is declared in, if any.
+	file *ConfigFile
+}
+
+// GetKind returns the kind of the service.
+func (s ServiceConfig) GetKind() string {
+	if s.Kind == "" {
+		return ServiceKindWeb
+	}
+
+	return s.Kind
+}
+
+// GetPort returns the port the service listens on.
+func (s ServiceConfig) GetPort() int {
+	if s.Port == 0 {
+		return DefaultServicePort
+	}
+
+	return s.Port
+}
+
+var invalidEnvironmentCharacters = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// URLVariable returns the name of the environment variable holding the URL
+// of the service or resource name, like API_URL for api. Services get one
+// for everything in their dependsOn.
+func URLVariable(name string) string {
+	return strings.Trim(invalidEnvironmentCharacters.ReplaceAllString(strings.ToUpper(name), "_"), "_") + "_URL"
 }
 
 type AppConfig struct {
-	Name        string          `yaml:"name" json:"name"`
-	Description string          `yaml:"description" json:"description"`
-	Services    []ServiceConfig `yaml:"services" json:"services"`
+	Schema          string            `yaml:"$schema,omitempty" json:"$schema,omitempty" description:"The JSON Schema the config file is validated against."`
+	Version         int               `yaml:"version,omitempty" json:"version,omitempty" jsonschema:"minimum=1" description:"The version of the config file format. Older files are upgraded with jacuik migrate."`
+	Name            string            `yaml:"name" json:"name" jsonschema:"required" description:"The name of the project."`
+	Description     string            `yaml:"description" json:"description" description:"A short description of the project."`
+	Vars            map[string]string `yaml:"vars,omitempty" json:"vars,omitempty" description:"Values that can be used anywhere in the config with ${var:name}. Values may themselves use ${env:NAME}, ${git:sha} and ${stack:output}."`
+	Include         []string          `yaml:"include,omitempty" json:"include,omitempty" description:"Paths, relative to this file, of more config files whose services and resources are added to the project. Service paths in included files are relative to the project directory."`
+	Services        []ServiceConfig   `yaml:"services" json:"services" description:"The services that make up the project."`
+	Resources       []ResourceConfig  `yaml:"resources,omitempty" json:"resources,omitempty" description:"The backing resources, like databases and queues, used by the services."`
+	Provider        string            `yaml:"provider,omitempty" json:"provider,omitempty" jsonschema:"default=ecs" description:"Where the project is deployed. ecs deploys to AWS ECS on Fargate."`
+	Backend         BackendConfig     `yaml:"backend,omitempty" json:"backend,omitempty" description:"Where the state of the deployed stack is kept. Without it the active Pulumi login is used."`
+	SecretsProvider string            `yaml:"secretsProvider,omitempty" json:"secretsProvider,omitempty" description:"How the stack's secrets are encrypted: default, passphrase or a key URL like awskms://alias/my-key?region=us-west-2. Run jacuik secrets rotate-provider after changing it."`
+	Network         NetworkConfig     `yaml:"network,omitempty" json:"network,omitempty" description:"Existing networking to deploy into, like a shared VPC, cluster or load balancer. Without it everything is created for the project."`
+
+	// file is the config file the config was loaded from, if any.
+	file *ConfigFile
+}
+
+// GetProvider returns the provider the project is deployed with.
+func (a *AppConfig) GetProvider() string {
+	if a.Provider == "" {
+		return ProviderECS
+	}
+
+	return a.Provider
+}
+
+// ProjectDirectory returns the directory containing the config file, paths
+// in the config are relative to it. A config that wasn't loaded from a file
+// belongs to the working directory.
+func (a *AppConfig) ProjectDirectory() (string, error) {
+	if a.file != nil {
+		return filepath.Dir(a.file.Path), nil
+	}
+
+	return os.Getwd()
+}
+
+// ConfigFiles returns the paths of the config file and of the files it
+// includes.
+func (a *AppConfig) ConfigFiles() []string {
+	if a.file == nil {
+		return nil
+	}
+
+	paths := []string{a.file.Path}
+	for _, included := range a.file.includes {
+		paths = append(paths, included.Path)
+	}
+
+	return paths
+}
+
+// CheckWritable returns an error when the file the config was loaded from
+// is at an older version. The config was upgraded in memory and writing it
+// back would migrate the file without the user seeing the changes.
+func (a *AppConfig) CheckWritable() error {
+	if a.file == nil || a.file.migratedFrom == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("The config file [%s] uses config version %d, run `jacuik migrate` before changing it.", filepath.Base(a.file.Path), a.file.migratedFrom)
 }
 
 func (a *AppConfig) WriteOutConfigFile(typ string) error {
+	err := a.CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	projectDirectory, err := a.ProjectDirectory()
+	if err != nil {
+		return err
+	}
+
+	// Write out the JSON Schema alongside the config so editors can
+	// validate and autocomplete it.
+	err = WriteJSONSchemaFile(filepath.Join(projectDirectory, JSONSchemaFilePath))
+	if err != nil {
+		return err
+	}
+
+	// Edit the file the config was loaded from in place so hand made
+	// changes to it are kept.
+	if a.file != nil && a.file.Type == typ {
+		err = a.file.Update(a)
+		if err != nil {
+			return err
+		}
+
+		return a.file.Save()
+	}
+
 	switch typ {
 	case "yaml":
-		return utils.WriteYAMLFile("schema.yaml", a)
+		a.Schema = ""
+		contents, err := yaml.Marshal(a)
+		if err != nil {
+			return err
+		}
+
+		return utils.WriteFile("schema.yaml", schemaComment(typ)+"\n"+string(contents))
 	case "json":
-		return utils.WriteJSONFile("schema.json", a)
+		// Encoding through a node leaves out an empty backend, which
+		// encoding/json can't do for a struct.
+		a.Schema = JSONSchemaFilePath
+		var document yaml.Node
+		err := document.Encode(a)
+		if err != nil {
+			return err
+		}
+
+		var buffer bytes.Buffer
+		writeJSONNode(&buffer, &document, defaultJSONIndent, 0)
+		return utils.WriteFile("schema.json", buffer.String()+"\n")
+	case "toml":
+		a.Schema = ""
+		var document yaml.Node
+		err := document.Encode(a)
+		if err != nil {
+			return err
+		}
+
+		var buffer bytes.Buffer
+		writeTOMLNode(&buffer, &document)
+		return utils.WriteFile("schema.toml", schemaComment(typ)+"\n"+buffer.String())
 	default:
 		return fmt.Errorf("Unknown file type supplied [%s].", typ)
 	}
@@ -34,47 +241,222 @@ func (a *AppConfig) AddService(service ServiceConfig) {
 	a.Services = append(a.Services, service)
 }
 
+// GetService returns the service with the given name.
+func (a *AppConfig) GetService(name string) (*ServiceConfig, bool) {
+	for i := range a.Services {
+		if a.Services[i].Name == name {
+			return &a.Services[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// ServiceReferences returns the names of the services that depend on the
+// service or resource with the given name.
+func (a *AppConfig) ServiceReferences(name string) []string {
+	var result []string
+	for _, svc := range a.Services {
+		for _, dependency := range svc.DependsOn {
+			if dependency == name {
+				result = append(result, svc.Name)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// RemoveService removes a service from the config. A service can't be
+// removed while other services still depend on it.
+func (a *AppConfig) RemoveService(name string) error {
+	if _, ok := a.GetService(name); !ok {
+		return fmt.Errorf("Service [%s] does not exist.", name)
+	}
+
+	references := a.ServiceReferences(name)
+	if len(references) > 0 {
+		return fmt.Errorf("Service [%s] is still referenced by [%s].", name, strings.Join(references, ", "))
+	}
+
+	var services []ServiceConfig
+	for _, svc := range a.Services {
+		if svc.Name != name {
+			services = append(services, svc)
+		}
+	}
+	a.Services = services
+
+	return nil
+}
+
+// DeletableServiceDirectory returns the directory a service is built from
+// when it can be deleted along with the service. Directories outside the
+// project, the project itself and directories other services are built
+// from can't be.
+func (a *AppConfig) DeletableServiceDirectory(name string) (string, error) {
+	svc, ok := a.GetService(name)
+	if !ok {
+		return "", fmt.Errorf("Service [%s] does not exist.", name)
+	}
+
+	projectDirectory, err := a.ProjectDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	serviceDirectory := filepath.Join(projectDirectory, svc.PathToDockerfile)
+	if !isWithin(projectDirectory, serviceDirectory) || serviceDirectory == projectDirectory {
+		return "", fmt.Errorf("Service [%s] is built from [%s] which isn't a directory in the project.", name, svc.PathToDockerfile)
+	}
+
+	var users []string
+	for _, other := range a.Services {
+		if other.Name != name && isWithin(serviceDirectory, filepath.Join(projectDirectory, other.PathToDockerfile)) {
+			users = append(users, other.Name)
+		}
+	}
+	if len(users) > 0 {
+		return "", fmt.Errorf("The directory [%s] of service [%s] is also used by [%s].", svc.PathToDockerfile, name, strings.Join(users, ", "))
+	}
+
+	return serviceDirectory, nil
+}
+
+// isWithin reports whether path is directory or inside it.
+func isWithin(directory, path string) bool {
+	relativePath, err := filepath.Rel(directory, path)
+	if err != nil {
+		return false
+	}
+
+	return relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// RemoveResource removes a resource from the config. A resource can't be
+// removed while services still depend on it.
+func (a *AppConfig) RemoveResource(name string) error {
+	references := a.ServiceReferences(name)
+	if len(references) > 0 {
+		return fmt.Errorf("Resource [%s] is still referenced by [%s].", name, strings.Join(references, ", "))
+	}
+
+	var resources []ResourceConfig
+	found := false
+	for _, resource := range a.Resources {
+		if resource.Name == name {
+			found = true
+			continue
+		}
+		resources = append(resources, resource)
+	}
+
+	if !found {
+		return fmt.Errorf("Resource [%s] does not exist.", name)
+	}
+	a.Resources = resources
+
+	return nil
+}
+
+// SaveConfigFile writes the config back to the file it was loaded from. The
+// changed config is validated first and nothing is written if it is invalid.
+func (a *AppConfig) SaveConfigFile() error {
+	if a.file == nil {
+		return fmt.Errorf("The config was not loaded from a file.")
+	}
+
+	err := a.CheckWritable()
+	if err != nil {
+		return err
+	}
+
+	err = a.file.Update(a)
+	if err != nil {
+		return err
+	}
+
+	validationErrors := a.file.Validate()
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	return a.file.Save()
+}
+
+// RenameService renames a service and updates every service that depends on it.
+func (a *AppConfig) RenameService(oldName, newName string) error {
+	svc, ok := a.GetService(oldName)
+	if !ok {
+		return fmt.Errorf("Service [%s] does not exist.", oldName)
+	}
+
+	if newName == "" {
+		return fmt.Errorf("A new name for service [%s] is required.", oldName)
+	}
+
+	if len(newName) > maxNameLength {
+		return fmt.Errorf("The service name [%s] is longer than %d characters.", newName, maxNameLength)
+	}
+
+	if !namePattern.MatchString(newName) {
+		return fmt.Errorf("The service name [%s] may only contain lowercase letters, numbers and dashes, and can't start or end with a dash.", newName)
+	}
+
+	if _, exists := a.GetService(newName); exists {
+		return fmt.Errorf("A service named [%s] already exists.", newName)
+	}
+
+	for _, resource := range a.Resources {
+		if resource.Name == newName {
+			return fmt.Errorf("A resource named [%s] already exists.", newName)
+		}
+	}
+
+	svc.Name = newName
+
+	for i := range a.Services {
+		for j, dependency := range a.Services[i].DependsOn {
+			if dependency == oldName {
+				a.Services[i].DependsOn[j] = newName
+			}
+		}
+	}
+
+	return nil
+}
+
 func ParseJacuikConfig() (*AppConfig, string, error) {
-	currentDirectory, err := os.Getwd()
+	filePath, configType, err := LocateConfigFile()
 	if err != nil {
 		return nil, "", err
 	}
 
-	directoryContents, err := utils.ReadDirectoryContents(currentDirectory)
+	validationErrors, err := ValidateConfigFile(filePath)
 	if err != nil {
 		return nil, "", err
 	}
+	if len(validationErrors) > 0 {
+		return nil, "", validationErrors
+	}
 
-	var config *AppConfig
-	var configType string
-	for _, directoryItem := range directoryContents {
-		switch directoryItem {
-		case "schema.yaml":
-			configType = "yaml"
-			filePath := fmt.Sprintf("%s/schema.yaml", currentDirectory)
-			config, err = utils.ParseYAMLFile[AppConfig](filePath)
-			if err != nil {
-				return nil, "", err
-			}
-		case "schema.yml":
-			configType = "yaml"
-			filePath := fmt.Sprintf("%s/schema.yml", currentDirectory)
-			config, err = utils.ParseYAMLFile[AppConfig](filePath)
-			if err != nil {
-				return nil, "", err
-			}
-		case "schema.json":
-			configType = "json"
-			filePath := fmt.Sprintf("%s/schema.json", currentDirectory)
-			config, err = utils.ParseJSONFile[AppConfig](filePath)
-			if err != nil {
-				return nil, "", err
-			}
-		}
+	file, err := LoadConfigFile(filePath, configType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Older files are upgraded in memory so the rest of the CLI only deals
+	// with the current format. jacuik migrate rewrites the file itself.
+	_, err = file.Migrate()
+	if err != nil {
+		return nil, "", err
 	}
 
-	if config == nil {
-		return nil, "", fmt.Errorf("The schema file is empty or was not found. Please ensure you are in a Jacuik project.")
+	// Decode merges the included files and resolves ${...} expressions.
+	config, err := file.Decode()
+	if err != nil {
+		return nil, "", err
 	}
 
 	return config, configType, nil
@@ -0,0 +1,448 @@
+package jacuik_config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// tomlBareKeyPattern matches keys that can be written without quotes.
+var tomlBareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// parseTOML parses a TOML document into a yaml.v3 node tree so TOML files
+// are validated, edited and converted the same way as YAML and JSON files.
+// Comments are kept as head and line comments.
+func parseTOML(contents []byte, document *yaml.Node) error {
+	// Decoding into a map checks the document against the whole TOML spec,
+	// like keys being redefined, which the parser below doesn't.
+	var raw map[string]interface{}
+	specErr := toml.Unmarshal(contents, &raw)
+	var decodeError *toml.DecodeError
+	if errors.As(specErr, &decodeError) {
+		return specErr
+	}
+
+	b := &tomlBuilder{root: &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1}}
+	b.table = b.root
+	b.parser.KeepComments = true
+	b.parser.Reset(contents)
+
+	for b.parser.NextExpression() {
+		b.addExpression(b.parser.Expression())
+	}
+	if err := b.parser.Error(); err != nil {
+		var parserError *unstable.ParserError
+		if errors.As(err, &parserError) && len(parserError.Highlight) > 0 {
+			shape := b.parser.Shape(b.parser.Range(parserError.Highlight))
+			return &tomlError{line: shape.Start.Line, column: shape.Start.Column, message: parserError.Message}
+		}
+		return err
+	}
+
+	// Errors about keys being defined twice don't have a position, point
+	// at the second definition when it can be found.
+	if specErr != nil {
+		message := strings.TrimPrefix(specErr.Error(), "toml: ")
+		if key := findDuplicateKey(b.root); key != nil {
+			return &tomlError{line: key.Line, column: key.Column, message: message}
+		}
+		return &tomlError{message: message}
+	}
+
+	if len(b.comments) > 0 {
+		b.root.FootComment = strings.Join(b.comments, "\n")
+	}
+
+	*document = yaml.Node{Kind: yaml.DocumentNode, Line: 1, Column: 1, Content: []*yaml.Node{b.root}}
+	return nil
+}
+
+// tomlError is a TOML error found at a position in the file.
+type tomlError struct {
+	line, column int
+	message      string
+}
+
+func (e *tomlError) Error() string {
+	return e.message
+}
+
+// findDuplicateKey returns the second definition of a key defined twice in
+// the same mapping.
+func findDuplicateKey(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.MappingNode {
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if seen[node.Content[i].Value] {
+				return node.Content[i]
+			}
+			seen[node.Content[i].Value] = true
+		}
+	}
+
+	for _, child := range node.Content {
+		if key := findDuplicateKey(child); key != nil {
+			return key
+		}
+	}
+
+	return nil
+}
+
+type tomlBuilder struct {
+	parser unstable.Parser
+	root   *yaml.Node
+	// table is the mapping key-values are currently added to.
+	table *yaml.Node
+	// comments are the comment lines waiting for the next expression.
+	comments []string
+}
+
+func (b *tomlBuilder) addExpression(expression *unstable.Node) {
+	switch expression.Kind {
+	case unstable.Comment:
+		b.comments = append(b.comments, strings.TrimRight(string(expression.Data), "\r"))
+		return
+
+	case unstable.KeyValue:
+		key, value := b.keyValue(b.table, expression)
+		key.HeadComment = b.takeComments()
+		value.LineComment = lineComment(expression)
+
+	case unstable.Table:
+		b.table = b.mappingAt(b.root, keyParts(expression.Key()))
+		b.table.HeadComment = b.takeComments()
+		b.table.LineComment = lineComment(expression)
+
+	case unstable.ArrayTable:
+		parts := keyParts(expression.Key())
+		parent := b.mappingAt(b.root, parts[:len(parts)-1])
+		last := parts[len(parts)-1]
+
+		list := mappingValue(parent, last.Data)
+		if list == nil {
+			list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			b.setPosition(list, last.Node)
+			parent.Content = append(parent.Content, b.keyNode(last), list)
+		}
+
+		item := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		b.setPosition(item, last.Node)
+		item.HeadComment = b.takeComments()
+		item.LineComment = lineComment(expression)
+		list.Content = append(list.Content, item)
+		b.table = item
+	}
+}
+
+func (b *tomlBuilder) takeComments() string {
+	comment := strings.Join(b.comments, "\n")
+	b.comments = nil
+	return comment
+}
+
+// lineComment returns the comment at the end of an expression's line.
+func lineComment(expression *unstable.Node) string {
+	if next := expression.Next(); next != nil && next.Kind == unstable.Comment {
+		return strings.TrimRight(string(next.Data), "\r")
+	}
+
+	return ""
+}
+
+type tomlKeyPart struct {
+	Data string
+	Node *unstable.Node
+}
+
+func keyParts(it unstable.Iterator) []tomlKeyPart {
+	var parts []tomlKeyPart
+	for it.Next() {
+		parts = append(parts, tomlKeyPart{Data: string(it.Node().Data), Node: it.Node()})
+	}
+
+	return parts
+}
+
+// mappingAt returns the mapping at a dotted key, creating it if needed. A
+// key holding a list of tables refers to its last table.
+func (b *tomlBuilder) mappingAt(node *yaml.Node, parts []tomlKeyPart) *yaml.Node {
+	for _, part := range parts {
+		child := mappingValue(node, part.Data)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			b.setPosition(child, part.Node)
+			node.Content = append(node.Content, b.keyNode(part), child)
+		}
+
+		if child.Kind == yaml.SequenceNode && len(child.Content) > 0 {
+			child = child.Content[len(child.Content)-1]
+		}
+		node = child
+	}
+
+	return node
+}
+
+// keyValue adds a key-value expression to table and returns its key and
+// value nodes.
+func (b *tomlBuilder) keyValue(table *yaml.Node, expression *unstable.Node) (*yaml.Node, *yaml.Node) {
+	parts := keyParts(expression.Key())
+	parent := b.mappingAt(table, parts[:len(parts)-1])
+	last := parts[len(parts)-1]
+
+	key := b.keyNode(last)
+	value := b.valueNode(expression.Value(), last.Node)
+	parent.Content = append(parent.Content, key, value)
+
+	return key, value
+}
+
+func (b *tomlBuilder) keyNode(part tomlKeyPart) *yaml.Node {
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part.Data}
+	b.setPosition(key, part.Node)
+	return key
+}
+
+// valueNode converts a TOML value. keyNode positions values, like arrays,
+// that the parser doesn't record a position for.
+func (b *tomlBuilder) valueNode(value *unstable.Node, keyNode *unstable.Node) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode}
+	if !b.setPosition(node, value) {
+		b.setPosition(node, keyNode)
+	}
+
+	switch value.Kind {
+	case unstable.String:
+		node.Tag = "!!str"
+		node.Style = yaml.DoubleQuotedStyle
+		node.Value = string(value.Data)
+	case unstable.Bool:
+		node.Tag = "!!bool"
+		node.Value = string(value.Data)
+	case unstable.Integer:
+		node.Tag = "!!int"
+		node.Value = strings.ReplaceAll(string(value.Data), "_", "")
+		if number, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			node.Value = strconv.FormatInt(number, 10)
+		}
+	case unstable.Float:
+		node.Tag = "!!float"
+		node.Value = strings.ReplaceAll(string(value.Data), "_", "")
+	case unstable.Array:
+		node.Kind = yaml.SequenceNode
+		node.Tag = "!!seq"
+		node.Style = yaml.FlowStyle
+		it := value.Children()
+		for it.Next() {
+			if it.Node().Kind == unstable.Comment {
+				continue
+			}
+			node.Content = append(node.Content, b.valueNode(it.Node(), keyNode))
+		}
+	case unstable.InlineTable:
+		node.Kind = yaml.MappingNode
+		node.Tag = "!!map"
+		node.Style = yaml.FlowStyle
+		it := value.Children()
+		for it.Next() {
+			if it.Node().Kind != unstable.Comment {
+				b.keyValue(node, it.Node())
+			}
+		}
+	default:
+		// Dates and times are kept as strings.
+		node.Tag = "!!str"
+		node.Value = string(value.Data)
+	}
+
+	return node
+}
+
+// setPosition sets the line and column of node from a parsed TOML node and
+// reports whether the parser recorded a position for it.
+func (b *tomlBuilder) setPosition(node *yaml.Node, from *unstable.Node) bool {
+	var r unstable.Range
+	switch {
+	case from.Raw.Length > 0:
+		r = from.Raw
+	case from.Kind == unstable.Bool:
+		// Booleans reference the input directly.
+		r = b.parser.Range(from.Data)
+	case from.Kind == unstable.Array || from.Kind == unstable.InlineTable:
+		child := from.Child()
+		if child == nil {
+			return false
+		}
+		return b.setPosition(node, child)
+	default:
+		return false
+	}
+
+	shape := b.parser.Shape(r)
+	node.Line = shape.Start.Line
+	node.Column = shape.Start.Column
+	return true
+}
+
+// writeTOMLNode writes a document as TOML. Mappings become tables and lists
+// of mappings become arrays of tables, everything else is written inline.
+func writeTOMLNode(buffer *bytes.Buffer, document *yaml.Node) {
+	root := document
+	if document.Kind == yaml.DocumentNode {
+		writeTOMLComment(buffer, document.HeadComment)
+		root = document.Content[0]
+	}
+
+	writeTOMLComment(buffer, root.HeadComment)
+	writeTOMLTable(buffer, root, nil)
+	writeTOMLComment(buffer, root.FootComment)
+}
+
+func writeTOMLTable(buffer *bytes.Buffer, node *yaml.Node, path []string) {
+	// TOML requires the plain key-values of a table to come before its
+	// sub-tables.
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if isTOMLTable(value) || isTOMLArrayOfTables(value) || isNull(value) {
+			continue
+		}
+
+		writeTOMLComment(buffer, key.HeadComment)
+		buffer.WriteString(tomlKey(key.Value))
+		buffer.WriteString(" = ")
+		writeTOMLValue(buffer, value)
+		writeTOMLLineComment(buffer, value.LineComment)
+		buffer.WriteString("\n")
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		tablePath := append(append([]string{}, path...), tomlKey(key.Value))
+
+		switch {
+		case isTOMLTable(value):
+			buffer.WriteString("\n")
+			writeTOMLComment(buffer, key.HeadComment)
+			writeTOMLComment(buffer, value.HeadComment)
+			buffer.WriteString(fmt.Sprintf("[%s]", strings.Join(tablePath, ".")))
+			writeTOMLLineComment(buffer, value.LineComment)
+			buffer.WriteString("\n")
+			writeTOMLTable(buffer, value, tablePath)
+		case isTOMLArrayOfTables(value):
+			for j, item := range value.Content {
+				buffer.WriteString("\n")
+				if j == 0 {
+					writeTOMLComment(buffer, key.HeadComment)
+				}
+				writeTOMLComment(buffer, item.HeadComment)
+				buffer.WriteString(fmt.Sprintf("[[%s]]", strings.Join(tablePath, ".")))
+				writeTOMLLineComment(buffer, item.LineComment)
+				buffer.WriteString("\n")
+				writeTOMLTable(buffer, item, tablePath)
+			}
+		}
+	}
+}
+
+func writeTOMLValue(buffer *bytes.Buffer, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		writeTOMLValue(buffer, node.Alias)
+	case yaml.MappingNode:
+		buffer.WriteString("{")
+		first := true
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if isNull(node.Content[i+1]) {
+				continue
+			}
+			if !first {
+				buffer.WriteString(",")
+			}
+			first = false
+			buffer.WriteString(" " + tomlKey(node.Content[i].Value) + " = ")
+			writeTOMLValue(buffer, node.Content[i+1])
+		}
+		if !first {
+			buffer.WriteString(" ")
+		}
+		buffer.WriteString("}")
+	case yaml.SequenceNode:
+		buffer.WriteString("[")
+		for i, item := range node.Content {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			writeTOMLValue(buffer, item)
+		}
+		buffer.WriteString("]")
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			buffer.WriteString(node.Value)
+		default:
+			// JSON strings are valid TOML basic strings.
+			writeJSONString(buffer, node.Value)
+		}
+	}
+}
+
+func writeTOMLComment(buffer *bytes.Buffer, comment string) {
+	if comment == "" {
+		return
+	}
+
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			line = "# " + line
+		}
+		buffer.WriteString(line + "\n")
+	}
+}
+
+func writeTOMLLineComment(buffer *bytes.Buffer, comment string) {
+	if comment == "" {
+		return
+	}
+
+	if !strings.HasPrefix(comment, "#") {
+		comment = "# " + comment
+	}
+	buffer.WriteString(" " + comment)
+}
+
+func isTOMLTable(node *yaml.Node) bool {
+	return node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0
+}
+
+func isTOMLArrayOfTables(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode || node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+		return false
+	}
+
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+
+	return true
+}
+
+func tomlKey(key string) string {
+	if tomlBareKeyPattern.MatchString(key) {
+		return key
+	}
+
+	var buffer bytes.Buffer
+	writeJSONString(&buffer, key)
+	return buffer.String()
+}
@@ -0,0 +1,664 @@
+package jacuik_config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	yaml "gopkg.in/yaml.v3"
+)
+
+var (
+	// Service and resource names end up in cloud resource names and DNS
+	// entries so they are limited to lowercase alphanumerics and dashes.
+	namePattern   = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	maxNameLength = 32
+
+	yamlErrorLinePattern = regexp.MustCompile(`line (\d+):`)
+)
+
+// ValidationError is a single problem found in a config file.
+type ValidationError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (v ValidationError) Error() string {
+	if v.Line == 0 {
+		return fmt.Sprintf("%s: %s", v.File, v.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", v.File, v.Line, v.Column, v.Message)
+}
+
+// ValidationErrors is every problem found in a config file.
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	var lines []string
+	for _, err := range v {
+		lines = append(lines, err.Error())
+	}
+
+	return fmt.Sprintf("The config file is invalid:\n\n%s\n", strings.Join(lines, "\n"))
+}
+
+// ValidateConfigFile checks the config file at filePath and returns all of
+// the problems found in it. A nil result means the file is valid.
+func ValidateConfigFile(filePath string) (ValidationErrors, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ValidateConfig(filePath, contents), nil
+}
+
+// ValidateConfig checks the contents of a config file. The file path is used
+// to report errors and to resolve the service paths in the config.
+func ValidateConfig(filePath string, contents []byte) ValidationErrors {
+	v := &validator{
+		file: filepath.Base(filePath),
+		dir:  filepath.Dir(filePath),
+	}
+
+	if strings.HasSuffix(filePath, ".json") {
+		var raw interface{}
+		err := json.Unmarshal(contents, &raw)
+		if err != nil {
+			v.addJSONError(contents, err)
+			return v.errors
+		}
+	}
+
+	var document yaml.Node
+	if strings.HasSuffix(filePath, ".toml") {
+		err := parseTOML(contents, &document)
+		if err != nil {
+			v.addTOMLError(err)
+			return v.errors
+		}
+	} else if err := yaml.Unmarshal(contents, &document); err != nil {
+		line := 0
+		if match := yamlErrorLinePattern.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+
+		v.errors = append(v.errors, ValidationError{
+			File:    v.file,
+			Line:    line,
+			Column:  1,
+			Message: strings.TrimPrefix(err.Error(), "yaml: "),
+		})
+		return v.errors
+	}
+
+	v.validateFile(&ConfigFile{Path: filePath, document: &document})
+	return v.errors
+}
+
+// Validate checks the file, and the files it includes, as they are in
+// memory. It is used to check changes before they are saved.
+func (c *ConfigFile) Validate() ValidationErrors {
+	v := &validator{
+		file: filepath.Base(c.Path),
+		dir:  filepath.Dir(c.Path),
+	}
+
+	v.validateFile(&ConfigFile{Path: c.Path, Type: c.Type, document: copyNode(c.document), includes: c.includes})
+	return v.errors
+}
+
+func (v *validator) validateFile(file *ConfigFile) {
+	if len(file.document.Content) == 0 {
+		v.errors = append(v.errors, ValidationError{File: v.file, Message: "the config file is empty"})
+		return
+	}
+
+	// Validate the upgraded document. Nodes that were already in the file
+	// keep their positions so errors still point at the original lines.
+	root := file.document.Content[0]
+	_, versionNode, err := DocumentVersion(root)
+	if err != nil {
+		if versionNode == nil {
+			versionNode = root
+		}
+		v.addError(versionNode, "%s", err.Error())
+		return
+	}
+
+	_, err = MigrateDocument(root)
+	if err != nil {
+		v.addError(root, "%s", err.Error())
+		return
+	}
+
+	// Validate the project with its includes merged in and its
+	// interpolations resolved.
+	resolved := file.resolve()
+	v.errors = append(v.errors, resolved.errors...)
+	v.nodeFiles = resolved.nodeFiles
+
+	v.validateApp(resolved.root)
+
+	// Errors in the file itself come first, then errors in included files.
+	sort.SliceStable(v.errors, func(x, y int) bool {
+		errX, errY := v.errors[x], v.errors[y]
+		if errX.File != errY.File {
+			if errX.File == v.file || errY.File == v.file {
+				return errX.File == v.file
+			}
+			return errX.File < errY.File
+		}
+		if errX.Line == errY.Line {
+			return errX.Column < errY.Column
+		}
+		return errX.Line < errY.Line
+	})
+}
+
+type validator struct {
+	file string
+	dir  string
+	// nodeFiles holds the file of nodes that came from included files.
+	nodeFiles map[*yaml.Node]string
+	errors    ValidationErrors
+}
+
+// fileOf returns the name of the file a node was read from.
+func (v *validator) fileOf(node *yaml.Node) string {
+	if file, ok := v.nodeFiles[node]; ok {
+		return file
+	}
+
+	return v.file
+}
+
+func (v *validator) addError(node *yaml.Node, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{
+		File:    v.fileOf(node),
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// addJSONError converts the byte offset reported by encoding/json into a
+// line and column.
+func (v *validator) addJSONError(contents []byte, err error) {
+	var offset int64
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &syntaxError) {
+		offset = syntaxError.Offset
+	} else if errors.As(err, &typeError) {
+		offset = typeError.Offset
+	}
+
+	// The offset is the number of bytes read, which includes the offending byte.
+	line, column := 1, 1
+	for i := int64(0); i < offset-1 && i < int64(len(contents)); i++ {
+		if contents[i] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+
+	v.errors = append(v.errors, ValidationError{
+		File:    v.file,
+		Line:    line,
+		Column:  column,
+		Message: err.Error(),
+	})
+}
+
+// addTOMLError adds a TOML syntax error at the position it was found.
+func (v *validator) addTOMLError(err error) {
+	line, column := 0, 0
+	var decodeError *toml.DecodeError
+	var positionedError *tomlError
+	if errors.As(err, &decodeError) {
+		line, column = decodeError.Position()
+	} else if errors.As(err, &positionedError) {
+		line, column = positionedError.line, positionedError.column
+	}
+
+	v.errors = append(v.errors, ValidationError{
+		File:    v.file,
+		Line:    line,
+		Column:  column,
+		Message: strings.TrimPrefix(err.Error(), "toml: "),
+	})
+}
+
+// mappingFields checks that node is a mapping with only known keys and
+// returns the value nodes keyed by field name.
+func (v *validator) mappingFields(node *yaml.Node, typ reflect.Type, description string) map[string]*yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		v.addError(node, "%s must be a mapping", description)
+		return nil
+	}
+
+	knownKeys := yamlFieldNames(typ)
+	fields := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		value := node.Content[i+1]
+
+		if _, ok := knownKeys[key.Value]; !ok {
+			v.addError(key, "unknown field %q in %s", key.Value, description)
+			continue
+		}
+
+		if _, ok := fields[key.Value]; ok {
+			v.addError(key, "field %q is set more than once in %s", key.Value, description)
+			continue
+		}
+
+		fields[key.Value] = value
+	}
+
+	return fields
+}
+
+func (v *validator) validateApp(node *yaml.Node) {
+	fields := v.mappingFields(node, reflect.TypeOf(AppConfig{}), "the project")
+	if fields == nil {
+		return
+	}
+
+	name, ok := fields["name"]
+	if !ok {
+		v.addError(node, "the project is missing a name")
+	} else if v.expectScalar(name, "!!str", "the project name") && strings.TrimSpace(name.Value) == "" {
+		v.addError(name, "the project name can't be empty")
+	}
+
+	if description, ok := fields["description"]; ok {
+		v.expectScalar(description, "!!str", "the project description")
+	}
+
+	if schema, ok := fields["$schema"]; ok {
+		v.expectScalar(schema, "!!str", "$schema")
+	}
+
+	if vars, ok := fields["vars"]; ok && !isNull(vars) {
+		if vars.Kind != yaml.MappingNode {
+			v.addError(vars, "vars must be a mapping")
+		} else {
+			for i := 0; i+1 < len(vars.Content); i += 2 {
+				value := vars.Content[i+1]
+				if value.Kind != yaml.ScalarNode || isNull(value) {
+					v.addError(value, "the var %q must be a string, number or boolean", vars.Content[i].Value)
+				}
+			}
+		}
+	}
+
+	if include, ok := fields["include"]; ok && !isNull(include) {
+		if include.Kind != yaml.SequenceNode {
+			v.addError(include, "include must be a list")
+		} else {
+			for _, item := range include.Content {
+				v.expectScalar(item, "!!str", "an included file")
+			}
+		}
+	}
+
+	if provider, ok := fields["provider"]; ok && v.expectScalar(provider, "!!str", "the provider") {
+		known := false
+		for _, name := range providerNames {
+			known = known || name == provider.Value
+		}
+		if !known {
+			v.addError(provider, "unknown provider %q, expected one of %s", provider.Value, strings.Join(providerNames, ", "))
+		}
+	}
+
+	if backend, ok := fields["backend"]; ok && !isNull(backend) {
+		v.validateBackend(backend)
+	}
+
+	if network, ok := fields["network"]; ok && !isNull(network) {
+		v.validateNetwork(network)
+	}
+
+	if secretsProvider, ok := fields["secretsProvider"]; ok && v.expectScalar(secretsProvider, "!!str", "the secrets provider") {
+		if message := validateSecretsProvider(secretsProvider.Value); message != "" {
+			v.addError(secretsProvider, "%s", message)
+		}
+	}
+
+	// Names of every service and resource mapped to the node that declared
+	// them so duplicates and references can be checked.
+	declared := make(map[string]*yaml.Node)
+	var dependencies []*yaml.Node
+
+	if resources, ok := fields["resources"]; ok && !isNull(resources) {
+		if resources.Kind != yaml.SequenceNode {
+			v.addError(resources, "resources must be a list")
+		} else {
+			for _, resource := range resources.Content {
+				v.validateResource(resource, declared)
+			}
+		}
+	}
+
+	if services, ok := fields["services"]; ok && !isNull(services) {
+		if services.Kind != yaml.SequenceNode {
+			v.addError(services, "services must be a list")
+		} else {
+			for _, service := range services.Content {
+				dependencies = append(dependencies, v.validateService(service, declared)...)
+			}
+		}
+	}
+
+	for _, dependency := range dependencies {
+		if _, ok := declared[dependency.Value]; !ok {
+			v.addError(dependency, "%q is not a service or resource in the project", dependency.Value)
+		}
+	}
+}
+
+// validateService checks a single service and returns the nodes of the
+// services and resources it depends on.
+func (v *validator) validateService(node *yaml.Node, declared map[string]*yaml.Node) []*yaml.Node {
+	fields := v.mappingFields(node, reflect.TypeOf(ServiceConfig{}), "the service")
+	if fields == nil {
+		return nil
+	}
+
+	serviceName := ""
+	description := "the service"
+	name, ok := fields["name"]
+	if !ok {
+		v.addError(node, "the service is missing a name")
+	} else if v.validateName(name, "service", declared) {
+		serviceName = name.Value
+		description = fmt.Sprintf("service %q", serviceName)
+	}
+
+	if kind, ok := fields["kind"]; ok && v.expectScalar(kind, "!!str", fmt.Sprintf("the kind of %s", description)) {
+		if kind.Value != ServiceKindWeb && kind.Value != ServiceKindWorker {
+			v.addError(kind, "the kind of %s must be one of %q or %q", description, ServiceKindWeb, ServiceKindWorker)
+		}
+	}
+
+	path, ok := fields["path"]
+	if !ok {
+		v.addError(node, "%s is missing a path", description)
+	} else if v.expectScalar(path, "!!str", fmt.Sprintf("the path of %s", description)) {
+		v.validatePath(path, description)
+	}
+
+	if public, ok := fields["public"]; ok {
+		v.expectScalar(public, "!!bool", fmt.Sprintf("the public setting of %s", description))
+	}
+
+	if port, ok := fields["port"]; ok && v.expectScalar(port, "!!int", fmt.Sprintf("the port of %s", description)) {
+		value, err := strconv.Atoi(port.Value)
+		if err != nil || value < 1 || value > 65535 {
+			v.addError(port, "the port of %s must be between 1 and 65535", description)
+		}
+	}
+
+	if env, ok := fields["env"]; ok && !isNull(env) {
+		if env.Kind != yaml.MappingNode {
+			v.addError(env, "env of %s must be a mapping", description)
+		} else {
+			for i := 0; i+1 < len(env.Content); i += 2 {
+				value := env.Content[i+1]
+				if value.Kind != yaml.ScalarNode || isNull(value) {
+					v.addError(value, "the env var %q of %s must be a string, number or boolean", env.Content[i].Value, description)
+				}
+			}
+		}
+	}
+
+	var dependencies []*yaml.Node
+	if dependsOn, ok := fields["dependsOn"]; ok && !isNull(dependsOn) {
+		if dependsOn.Kind != yaml.SequenceNode {
+			v.addError(dependsOn, "dependsOn of %s must be a list", description)
+		} else {
+			for _, dependency := range dependsOn.Content {
+				if !v.expectScalar(dependency, "!!str", fmt.Sprintf("a dependency of %s", description)) {
+					continue
+				}
+
+				if serviceName != "" && dependency.Value == serviceName {
+					v.addError(dependency, "%s can't depend on itself", description)
+					continue
+				}
+
+				dependencies = append(dependencies, dependency)
+			}
+		}
+	}
+
+	return dependencies
+}
+
+func (v *validator) validateResource(node *yaml.Node, declared map[string]*yaml.Node) {
+	fields := v.mappingFields(node, reflect.TypeOf(ResourceConfig{}), "the resource")
+	if fields == nil {
+		return
+	}
+
+	description := "the resource"
+	name, ok := fields["name"]
+	if !ok {
+		v.addError(node, "the resource is missing a name")
+	} else if v.validateName(name, "resource", declared) {
+		description = fmt.Sprintf("resource %q", name.Value)
+	}
+
+	typ, ok := fields["type"]
+	if !ok {
+		v.addError(node, "%s is missing a type", description)
+	} else if v.expectScalar(typ, "!!str", fmt.Sprintf("the type of %s", description)) {
+		switch typ.Value {
+		case ResourceTypePostgres, ResourceTypeRedis, ResourceTypeQueue:
+		default:
+			v.addError(typ, "the type of %s must be one of %q, %q or %q", description, ResourceTypePostgres, ResourceTypeRedis, ResourceTypeQueue)
+		}
+	}
+}
+
+func (v *validator) validateBackend(node *yaml.Node) {
+	fields := v.mappingFields(node, reflect.TypeOf(BackendConfig{}), "the backend")
+	if fields == nil {
+		return
+	}
+
+	backend := BackendConfig{}
+	backendURL, ok := fields["url"]
+	if !ok {
+		v.addError(node, "the backend is missing a url")
+	} else if v.expectScalar(backendURL, "!!str", "the backend url") {
+		if message := validateBackendURL(backendURL.Value); message != "" {
+			v.addError(backendURL, "%s", message)
+		}
+		backend.URL = backendURL.Value
+	}
+
+	if organization, ok := fields["organization"]; ok && v.expectScalar(organization, "!!str", "the backend organization") {
+		if backend.URL != "" && !backend.IsCloud() {
+			v.addError(organization, "the backend organization is only used with Pulumi Cloud")
+		}
+	}
+}
+
+func (v *validator) validateNetwork(node *yaml.Node) {
+	fields := v.mappingFields(node, reflect.TypeOf(NetworkConfig{}), "the network")
+	if fields == nil {
+		return
+	}
+
+	vpcID, hasVpc := fields["vpcId"]
+	if hasVpc && v.expectScalar(vpcID, "!!str", "the network vpcId") && !vpcIDPattern.MatchString(vpcID.Value) {
+		v.addError(vpcID, "the network vpcId %q isn't a VPC ID like vpc-0123456789abcdef0", vpcID.Value)
+	}
+
+	subnetIDs, hasSubnets := fields["subnetIds"]
+	hasSubnets = hasSubnets && !isNull(subnetIDs)
+	if hasSubnets {
+		if !hasVpc {
+			v.addError(subnetIDs, "the network subnetIds are only used with a vpcId")
+		}
+
+		if subnetIDs.Kind != yaml.SequenceNode {
+			v.addError(subnetIDs, "the network subnetIds must be a list")
+		} else {
+			for _, subnetID := range subnetIDs.Content {
+				if v.expectScalar(subnetID, "!!str", "a network subnet ID") && !subnetIDPattern.MatchString(subnetID.Value) {
+					v.addError(subnetID, "the network subnet %q isn't a subnet ID like subnet-0123456789abcdef0", subnetID.Value)
+				}
+			}
+		}
+	}
+
+	if assignPublicIP, ok := fields["assignPublicIp"]; ok {
+		v.expectScalar(assignPublicIP, "!!bool", "the network assignPublicIp")
+	}
+
+	if cluster, ok := fields["cluster"]; ok && v.expectScalar(cluster, "!!str", "the network cluster") && !clusterPattern.MatchString(cluster.Value) {
+		v.addError(cluster, "the network cluster %q isn't the name or ARN of an ECS cluster", cluster.Value)
+	}
+
+	loadBalancerArn, hasLoadBalancer := fields["loadBalancerArn"]
+	listenerArn, hasListener := fields["listenerArn"]
+
+	// A new load balancer would otherwise be placed in every subnet of the
+	// VPC, private ones included.
+	noSubnets := !hasSubnets || (subnetIDs.Kind == yaml.SequenceNode && len(subnetIDs.Content) == 0)
+	if hasVpc && noSubnets && !hasLoadBalancer {
+		v.addError(vpcID, "the network vpcId needs subnetIds for the new load balancer, or an existing loadBalancerArn")
+	}
+
+	if hasLoadBalancer && v.expectScalar(loadBalancerArn, "!!str", "the network loadBalancerArn") {
+		if !loadBalancerArnPattern.MatchString(loadBalancerArn.Value) {
+			v.addError(loadBalancerArn, "the network loadBalancerArn %q isn't the ARN of an application load balancer", loadBalancerArn.Value)
+		}
+
+		// The services have to run in the load balancer's VPC.
+		if !hasVpc {
+			v.addError(loadBalancerArn, "the network loadBalancerArn needs the vpcId of the load balancer")
+		}
+
+		if !hasListener {
+			v.addError(loadBalancerArn, "the network loadBalancerArn needs a listenerArn to forward to the services")
+		}
+	}
+
+	if hasListener && v.expectScalar(listenerArn, "!!str", "the network listenerArn") {
+		switch {
+		case !hasLoadBalancer:
+			v.addError(listenerArn, "the network listenerArn is only used with a loadBalancerArn")
+		case !listenerArnPattern.MatchString(listenerArn.Value):
+			v.addError(listenerArn, "the network listenerArn %q isn't the ARN of an application load balancer listener", listenerArn.Value)
+		case !listenerBelongsTo(listenerArn.Value, loadBalancerArn.Value):
+			v.addError(listenerArn, "the network listenerArn isn't a listener of loadBalancerArn")
+		}
+	}
+}
+
+// validateName checks a service or resource name and records it as declared.
+// It returns true when the name is usable in other error messages.
+func (v *validator) validateName(name *yaml.Node, kind string, declared map[string]*yaml.Node) bool {
+	if !v.expectScalar(name, "!!str", fmt.Sprintf("the %s name", kind)) {
+		return false
+	}
+
+	if name.Value == "" {
+		v.addError(name, "the %s name can't be empty", kind)
+		return false
+	}
+
+	if len(name.Value) > maxNameLength {
+		v.addError(name, "the %s name %q is longer than %d characters", kind, name.Value, maxNameLength)
+	}
+
+	if !namePattern.MatchString(name.Value) {
+		v.addError(name, "the %s name %q may only contain lowercase letters, numbers and dashes, and can't start or end with a dash", kind, name.Value)
+	}
+
+	if previous, ok := declared[name.Value]; ok {
+		if previousFile := v.fileOf(previous); previousFile != v.fileOf(name) {
+			v.addError(name, "%q is already declared in %s on line %d", name.Value, previousFile, previous.Line)
+		} else {
+			v.addError(name, "%q is already declared on line %d", name.Value, previous.Line)
+		}
+	} else {
+		declared[name.Value] = name
+	}
+
+	return true
+}
+
+func (v *validator) validatePath(path *yaml.Node, description string) {
+	if strings.TrimSpace(path.Value) == "" {
+		v.addError(path, "the path of %s can't be empty", description)
+		return
+	}
+
+	servicePath := filepath.Join(v.dir, path.Value)
+	info, err := os.Stat(servicePath)
+	if err != nil || !info.IsDir() {
+		v.addError(path, "the path %q of %s is not a directory", path.Value, description)
+		return
+	}
+
+	_, err = os.Stat(filepath.Join(servicePath, "Dockerfile"))
+	if err != nil {
+		v.addError(path, "no Dockerfile found in %q for %s", path.Value, description)
+	}
+}
+
+// expectScalar checks that node is a scalar with the given tag.
+func (v *validator) expectScalar(node *yaml.Node, tag string, description string) bool {
+	typeNames := map[string]string{
+		"!!str":  "a string",
+		"!!bool": "true or false",
+		"!!int":  "a whole number",
+	}
+
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != tag {
+		v.addError(node, "%s must be %s", description, typeNames[tag])
+		return false
+	}
+
+	return true
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null"
+}
+
+// yamlFieldNames returns the yaml names of the fields of a struct.
+func yamlFieldNames(typ reflect.Type) map[string]reflect.StructField {
+	result := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		result[name] = field
+	}
+
+	return result
+}
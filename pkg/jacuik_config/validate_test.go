@@ -0,0 +1,283 @@
+package jacuik_config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeProject writes files, keyed by their path relative to the project,
+// to a new directory and returns it.
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, contents := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		config string
+		// errors are the expected errors as file:line:col: message.
+		errors []string
+	}{
+		{
+			name: "valid",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+    public: true
+    dependsOn: [db]
+resources:
+  - name: db
+    type: postgres
+`,
+		},
+		{
+			name: "unknown field",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+servics: []
+`,
+			errors: []string{`schema.yaml:3:1: unknown field "servics" in the project`},
+		},
+		{
+			name: "missing name",
+			file: "schema.yaml",
+			config: `version: 2
+services: []
+`,
+			errors: []string{"schema.yaml:1:1: the project is missing a name"},
+		},
+		{
+			name: "invalid service",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+services:
+  - name: Api_1
+    path: ./api
+    port: 70000
+    public: yes please
+`,
+			errors: []string{
+				`schema.yaml:4:11: the service name "Api_1" may only contain lowercase letters, numbers and dashes, and can't start or end with a dash`,
+				`schema.yaml:6:11: the port of service "Api_1" must be between 1 and 65535`,
+				`schema.yaml:7:13: the public setting of service "Api_1" must be true or false`,
+			},
+		},
+		{
+			name: "name too long",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+services:
+  - name: a-service-name-that-is-far-too-long
+    path: ./api
+`,
+			errors: []string{`schema.yaml:4:11: the service name "a-service-name-that-is-far-too-long" is longer than 32 characters`},
+		},
+		{
+			name: "missing directory and Dockerfile",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+services:
+  - name: api
+    path: ./missing
+  - name: web
+    path: ./web
+`,
+			errors: []string{
+				`schema.yaml:5:11: the path "./missing" of service "api" is not a directory`,
+				`schema.yaml:7:11: no Dockerfile found in "./web" for service "web"`,
+			},
+		},
+		{
+			name: "duplicates and unknown dependencies",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+    dependsOn: [api, cache]
+  - name: api
+    path: ./api
+resources:
+  - name: db
+    type: mysql
+`,
+			errors: []string{
+				`schema.yaml:6:17: service "api" can't depend on itself`,
+				`schema.yaml:6:22: "cache" is not a service or resource in the project`,
+				`schema.yaml:7:11: "api" is already declared on line 4`,
+				`schema.yaml:11:11: the type of resource "db" must be one of "postgres", "redis" or "queue"`,
+			},
+		},
+		{
+			name: "existing vpc without subnets",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+network:
+  vpcId: vpc-0123456789abcdef0
+`,
+			errors: []string{`schema.yaml:4:10: the network vpcId needs subnetIds for the new load balancer, or an existing loadBalancerArn`},
+		},
+		{
+			name: "existing vpc with empty subnets",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+network:
+  vpcId: vpc-0123456789abcdef0
+  subnetIds: []
+`,
+			errors: []string{`schema.yaml:4:10: the network vpcId needs subnetIds for the new load balancer, or an existing loadBalancerArn`},
+		},
+		{
+			name: "existing vpc and load balancer",
+			file: "schema.yaml",
+			config: `version: 2
+name: demo
+network:
+  vpcId: vpc-0123456789abcdef0
+  loadBalancerArn: arn:aws:elasticloadbalancing:us-west-2:123456789012:loadbalancer/app/shared/0123456789abcdef
+  listenerArn: arn:aws:elasticloadbalancing:us-west-2:123456789012:listener/app/shared/0123456789abcdef/0123456789abcdef
+`,
+		},
+		{
+			name: "newer version",
+			file: "schema.yaml",
+			config: `version: 99
+name: demo
+`,
+			errors: []string{"schema.yaml:1:10: version 99 is newer than this CLI supports (2), please upgrade jacuik"},
+		},
+		{
+			name:   "yaml syntax",
+			file:   "schema.yaml",
+			config: "name: demo\nservices: [\n",
+			errors: []string{"schema.yaml:2:1: line 2: did not find expected node content"},
+		},
+		{
+			name: "json syntax",
+			file: "schema.json",
+			config: `{
+  "version": 2,
+  "name": "demo",
+}
+`,
+			errors: []string{"schema.json:4:1: invalid character '}' looking for beginning of object key string"},
+		},
+		{
+			name: "toml",
+			file: "schema.toml",
+			config: `version = 2
+name = "demo"
+
+[[services]]
+name = "api"
+path = "./api"
+port = "8080"
+`,
+			errors: []string{`schema.toml:7:8: the port of service "api" must be a whole number`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeProject(t, map[string]string{
+				test.file:        test.config,
+				"api/Dockerfile": "FROM scratch\n",
+				"web/index.html": "",
+			})
+
+			var got []string
+			for _, err := range ValidateConfig(filepath.Join(dir, test.file), []byte(test.config)) {
+				got = append(got, err.Error())
+			}
+
+			if strings.Join(got, "\n") != strings.Join(test.errors, "\n") {
+				t.Errorf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.errors, "\n"))
+			}
+		})
+	}
+}
+
+// TestExamples checks that every config file in examples/ is valid and can
+// be loaded.
+func TestExamples(t *testing.T) {
+	examples, err := filepath.Abs(filepath.Join("..", "..", "examples"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := 0
+	err = filepath.Walk(examples, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		isConfigFile := false
+		for _, name := range configFileNames {
+			isConfigFile = isConfigFile || info.Name() == name
+		}
+		if !isConfigFile {
+			return nil
+		}
+
+		found++
+		name, _ := filepath.Rel(examples, filePath)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			validationErrors, err := ValidateConfigFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(validationErrors) > 0 {
+				t.Fatal(validationErrors)
+			}
+
+			typ, err := configTypeFromPath(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := LoadConfigFile(filePath, typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := file.Decode(); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if found == 0 {
+		t.Fatal("no config files found in examples/")
+	}
+}
@@ -0,0 +1,83 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// invalidNameCharacters matches what can't be used in a Docker container,
+// network or image name.
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// dockerName joins parts into a name Docker accepts for containers,
+// networks and images.
+func dockerName(parts ...string) string {
+	name := strings.ToLower(strings.Join(parts, "-"))
+	return strings.Trim(invalidNameCharacters.ReplaceAllString(name, "-"), "-._")
+}
+
+// docker runs the docker CLI and returns what it printed, trimmed.
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := dockerStream(ctx, &stdout, args...)
+	return strings.TrimSpace(stdout.String()), err
+}
+
+// dockerStream runs the docker CLI writing its output to w. Errors hold what
+// it printed to stderr.
+func dockerStream(ctx context.Context, w io.Writer, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if w != nil && args[0] == "build" {
+		// Build progress is written to stderr.
+		cmd.Stderr = io.MultiWriter(w, &stderr)
+	}
+
+	err := cmd.Run()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("Docker is not installed, it is needed to run the project locally.")
+		}
+
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			lines := strings.Split(message, "\n")
+			return fmt.Errorf("docker %s failed: %s", args[0], lines[len(lines)-1])
+		}
+
+		return fmt.Errorf("docker %s failed: %w", args[0], err)
+	}
+
+	return nil
+}
+
+// checkDocker returns an error when the Docker daemon can't be reached.
+func checkDocker(ctx context.Context) error {
+	_, err := docker(ctx, "version", "--format", "{{.Server.Version}}")
+	return err
+}
+
+// envArgs returns the --env flags for an environment, sorted so the same
+// environment always gives the same arguments.
+func envArgs(environment map[string]string) []string {
+	names := make([]string, 0, len(environment))
+	for name := range environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, name := range names {
+		args = append(args, "--env", name+"="+environment[name])
+	}
+
+	return args
+}
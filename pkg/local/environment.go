@@ -0,0 +1,445 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zchase/jacuik/pkg/convert"
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+// projectLabel is set on everything Environment creates so what a previous
+// run left behind can be found and removed.
+const projectLabel = "dev.jacuik.project"
+
+// Environment runs every service of a project locally with Docker. The
+// services share a network where they reach each other by name, public web
+// services are exposed on local ports starting at ProxyPort. Resources are
+// replaced by the same stand-ins as in `jacuik export compose`.
+//
+// It isn't an infrastructure.Provider: nothing is deployed or kept in a
+// stack, the environment only lives as long as jacuik dev runs.
+type Environment struct {
+	Config    *jacuik_config.AppConfig
+	ProxyPort int
+	// OnStatus, if set, is called whenever a service is built, started or
+	// fails to.
+	OnStatus func(ServiceStatus)
+
+	network   string
+	logs      *logPrinter
+	services  []*localService
+	resources []*localResource
+}
+
+// localResource is a resource of the project and the container standing in
+// for it.
+type localResource struct {
+	config    jacuik_config.ResourceConfig
+	container string
+	// volume keeps the data of the stand-in from one run to the next, it
+	// is empty when the stand-in keeps nothing worth a volume.
+	volume string
+}
+
+// localService is a service of the project and the container it runs in.
+type localService struct {
+	config    jacuik_config.ServiceConfig
+	image     string
+	container string
+	// proxy is nil for services that aren't exposed.
+	proxy *proxy
+
+	mu   sync.Mutex
+	logs *exec.Cmd
+}
+
+// The states a service goes through.
+const (
+	StateBuilding = "building"
+	StateRunning  = "running"
+	StateFailed   = "failed"
+)
+
+// ServiceStatus is the state of a service in the environment.
+type ServiceStatus struct {
+	Service string
+	State   string
+	// Message says what the state is about, like the file that changed or
+	// why the build failed.
+	Message string
+	// URL is the local URL of a public service.
+	URL  string
+	Time time.Time
+}
+
+// NewEnvironment returns an environment for config. The logs of every
+// service are written to logs.
+func NewEnvironment(config *jacuik_config.AppConfig, proxyPort int, logs io.Writer) *Environment {
+	env := &Environment{
+		Config:    config,
+		ProxyPort: proxyPort,
+		network:   dockerName(config.Name, "dev"),
+	}
+
+	var names []string
+	nextPort := proxyPort
+	for _, svc := range config.Services {
+		service := &localService{
+			config:    svc,
+			image:     dockerName(config.Name, svc.Name) + ":dev",
+			container: dockerName(config.Name, svc.Name, "dev"),
+		}
+
+		if svc.Public && svc.GetKind() != jacuik_config.ServiceKindWorker {
+			service.proxy = newProxy(nextPort)
+			nextPort++
+		}
+
+		env.services = append(env.services, service)
+		names = append(names, svc.Name)
+	}
+	env.logs = newLogPrinter(logs, names)
+
+	for _, resource := range config.Resources {
+		env.resources = append(env.resources, &localResource{
+			config:    resource,
+			container: dockerName(config.Name, resource.Name, "dev"),
+			volume:    dockerName(config.Name, resource.Name, "data"),
+		})
+	}
+
+	return env
+}
+
+// Start builds and runs every service. Call Stop to remove what was
+// started, even when Start fails.
+func (e *Environment) Start(ctx context.Context) error {
+	err := checkDocker(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = e.removeLeftovers(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = docker(ctx, "network", "create", "--label", e.label(), e.network)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range e.services {
+		if service.proxy != nil {
+			err = service.proxy.start()
+			if err != nil {
+				return fmt.Errorf("couldn't expose service [%s]: %w", service.config.Name, err)
+			}
+		}
+	}
+
+	for _, resource := range e.resources {
+		err = e.runResource(ctx, resource)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Services start after the services they depend on.
+	for _, service := range e.startOrder() {
+		e.setStatus(service, StateBuilding, "")
+
+		err = e.build(ctx, service)
+		if err == nil {
+			err = e.run(ctx, service)
+		}
+		if err != nil {
+			e.setStatus(service, StateFailed, err.Error())
+			return err
+		}
+
+		e.setStatus(service, StateRunning, "")
+	}
+
+	return nil
+}
+
+// startOrder returns the services with every service after the services it
+// depends on, otherwise in the order they are declared.
+func (e *Environment) startOrder() []*localService {
+	byName := make(map[string]*localService)
+	for _, service := range e.services {
+		byName[service.config.Name] = service
+	}
+
+	var ordered []*localService
+	visited := make(map[string]bool)
+	var visit func(service *localService)
+	visit = func(service *localService) {
+		if visited[service.config.Name] {
+			return
+		}
+		visited[service.config.Name] = true
+
+		for _, dependency := range service.config.DependsOn {
+			if dependencyService, ok := byName[dependency]; ok {
+				visit(dependencyService)
+			}
+		}
+
+		ordered = append(ordered, service)
+	}
+
+	for _, service := range e.services {
+		visit(service)
+	}
+
+	return ordered
+}
+
+// runResource starts the stand-in of a resource, reachable by the name of
+// the resource on the network.
+func (e *Environment) runResource(ctx context.Context, resource *localResource) error {
+	standIn, err := convert.StandInFor(resource.config)
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"run", "--detach",
+		"--name", resource.container,
+		"--label", e.label(),
+		"--network", e.network,
+		"--network-alias", resource.config.Name,
+	}
+	args = append(args, envArgs(standIn.Environment)...)
+	if standIn.DataPath != "" {
+		args = append(args, "--volume", resource.volume+":"+standIn.DataPath)
+	}
+	args = append(args, standIn.Image)
+
+	_, err = docker(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("couldn't start resource [%s]: %w", resource.config.Name, err)
+	}
+
+	return nil
+}
+
+// Rebuild builds the image of the named service again and replaces its
+// container. The running container is kept when the build fails. reason
+// is shown in the status of the service.
+func (e *Environment) Rebuild(ctx context.Context, name, reason string) error {
+	var service *localService
+	for _, candidate := range e.services {
+		if candidate.config.Name == name {
+			service = candidate
+		}
+	}
+	if service == nil {
+		return fmt.Errorf("Service [%s] does not exist.", name)
+	}
+
+	started := time.Now()
+	e.setStatus(service, StateBuilding, reason)
+
+	err := e.build(ctx, service)
+	if err == nil {
+		err = e.restart(ctx, service)
+	}
+	if err != nil {
+		e.setStatus(service, StateFailed, err.Error())
+		return err
+	}
+
+	e.setStatus(service, StateRunning, fmt.Sprintf("rebuilt in %s", time.Since(started).Round(100*time.Millisecond)))
+	return nil
+}
+
+// restart replaces the container of the service with one from the image
+// that was just built. The proxy answers with a 503 in between.
+func (e *Environment) restart(ctx context.Context, service *localService) error {
+	if service.proxy != nil {
+		service.proxy.setTarget("")
+	}
+	service.stopLogs()
+
+	_, err := docker(ctx, "rm", "--force", service.container)
+	if err != nil {
+		return fmt.Errorf("couldn't stop service [%s]: %w", service.config.Name, err)
+	}
+
+	return e.run(ctx, service)
+}
+
+func (e *Environment) setStatus(service *localService, state, message string) {
+	if e.OnStatus == nil {
+		return
+	}
+
+	status := ServiceStatus{
+		Service: service.config.Name,
+		State:   state,
+		Message: message,
+		Time:    time.Now(),
+	}
+	if service.proxy != nil {
+		status.URL = service.proxy.url()
+	}
+
+	e.OnStatus(status)
+}
+
+// Stop removes the containers and the network and closes the local ports.
+func (e *Environment) Stop() error {
+	ctx := context.Background()
+
+	for _, service := range e.services {
+		if service.proxy != nil {
+			service.proxy.stop()
+		}
+	}
+
+	err := e.removeLeftovers(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, service := range e.services {
+		service.stopLogs()
+	}
+
+	return nil
+}
+
+func (e *Environment) label() string {
+	return fmt.Sprintf("%s=%s", projectLabel, dockerName(e.Config.Name))
+}
+
+// removeLeftovers removes the containers and the network of the project
+// that are still around, from this run or one that didn't stop cleanly.
+func (e *Environment) removeLeftovers(ctx context.Context) error {
+	containers, err := docker(ctx, "ps", "--all", "--quiet", "--filter", "label="+e.label())
+	if err != nil {
+		return err
+	}
+
+	if containers != "" {
+		_, err = docker(ctx, append([]string{"rm", "--force"}, strings.Fields(containers)...)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	networks, err := docker(ctx, "network", "ls", "--quiet", "--filter", "label="+e.label())
+	if err != nil {
+		return err
+	}
+
+	if networks != "" {
+		_, err = docker(ctx, append([]string{"network", "rm"}, strings.Fields(networks)...)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// build builds the image of the service from its Dockerfile, the output is
+// written to the service's logs.
+func (e *Environment) build(ctx context.Context, service *localService) error {
+	projectDirectory, err := e.Config.ProjectDirectory()
+	if err != nil {
+		return err
+	}
+
+	buildContext := filepath.Join(projectDirectory, service.config.PathToDockerfile)
+	err = dockerStream(ctx, e.logs.writer(service.config.Name), "build", "--tag", service.image, "--label", e.label(), buildContext)
+	if err != nil {
+		return fmt.Errorf("couldn't build service [%s]: %w", service.config.Name, err)
+	}
+
+	return nil
+}
+
+// run starts the container of the service and follows its logs. Exposed
+// services are published on a random port the proxy forwards to.
+func (e *Environment) run(ctx context.Context, service *localService) error {
+	port := strconv.Itoa(service.config.GetPort())
+
+	// The services get the same environment as in the compose export, with
+	// the URLs of the stand-ins of the resources they depend on.
+	environment, err := convert.ServiceEnvironment(e.Config, service.config)
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"run", "--detach",
+		"--name", service.container,
+		"--label", e.label(),
+		"--network", e.network,
+		"--network-alias", service.config.Name,
+	}
+	args = append(args, envArgs(environment)...)
+	if service.proxy != nil {
+		args = append(args, "--publish", "127.0.0.1::"+port)
+	}
+	args = append(args, service.image)
+
+	_, err = docker(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("couldn't start service [%s]: %w", service.config.Name, err)
+	}
+
+	if service.proxy != nil {
+		// docker port prints one address per line, IPv4 first.
+		addresses, err := docker(ctx, "port", service.container, port+"/tcp")
+		if err != nil {
+			return fmt.Errorf("couldn't expose service [%s]: %w", service.config.Name, err)
+		}
+
+		service.proxy.setTarget(strings.Split(addresses, "\n")[0])
+	}
+
+	return service.followLogs(e.logs.writer(service.config.Name))
+}
+
+// followLogs streams the logs of the container to w until the container is
+// removed.
+func (s *localService) followLogs(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd := exec.Command("docker", "logs", "--follow", s.container)
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	err := cmd.Start()
+	if err != nil {
+		return fmt.Errorf("couldn't follow the logs of service [%s]: %w", s.config.Name, err)
+	}
+	s.logs = cmd
+
+	go cmd.Wait()
+	return nil
+}
+
+func (s *localService) stopLogs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.logs != nil && s.logs.Process != nil {
+		s.logs.Process.Kill()
+	}
+	s.logs = nil
+}
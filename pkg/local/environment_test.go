@@ -0,0 +1,63 @@
+package local
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+func TestStartOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		services []jacuik_config.ServiceConfig
+		want     string
+	}{
+		{
+			name: "dependencies first",
+			services: []jacuik_config.ServiceConfig{
+				{Name: "web", DependsOn: []string{"api"}},
+				{Name: "api", DependsOn: []string{"auth", "db"}},
+				{Name: "auth"},
+				{Name: "worker", DependsOn: []string{"db", "auth"}},
+			},
+			want: "auth,api,web,worker",
+		},
+		{
+			name: "config order without dependencies",
+			services: []jacuik_config.ServiceConfig{
+				{Name: "b"},
+				{Name: "a"},
+				{Name: "c"},
+			},
+			want: "b,a,c",
+		},
+		{
+			// The config can't have cycles, they are still started once.
+			name: "cycle",
+			services: []jacuik_config.ServiceConfig{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+			want: "b,a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := &Environment{}
+			for _, service := range test.services {
+				e.services = append(e.services, &localService{config: service})
+			}
+
+			var names []string
+			for _, service := range e.startOrder() {
+				names = append(names, service.config.Name)
+			}
+
+			if got := strings.Join(names, ","); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
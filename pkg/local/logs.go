@@ -0,0 +1,80 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+// logColors are given to the services in turn so their logs can be told
+// apart.
+var logColors = []string{"#25a78b", "#f7bf2a", "#6b8afd", "#e53e3e", "#c678dd", "#56b6c2", "#d19a66", "#98c379"}
+
+// logPrinter writes the logs of every service to a single writer, each line
+// prefixed with the colored name of the service it came from.
+type logPrinter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	width  int
+	colors map[string]string
+}
+
+func newLogPrinter(out io.Writer, names []string) *logPrinter {
+	printer := &logPrinter{
+		out:    out,
+		colors: make(map[string]string),
+	}
+
+	for i, name := range names {
+		printer.colors[name] = logColors[i%len(logColors)]
+		if len(name) > printer.width {
+			printer.width = len(name)
+		}
+	}
+
+	return printer
+}
+
+// writer returns a writer whose lines are printed as coming from name.
+func (p *logPrinter) writer(name string) io.Writer {
+	return &prefixWriter{printer: p, name: name}
+}
+
+func (p *logPrinter) printLine(name string, line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prefix := utils.TextColor(fmt.Sprintf("%-*s |", p.width, name), p.colors[name])
+	fmt.Fprintf(p.out, "%s %s\n", prefix, bytes.TrimRight(line, "\r"))
+}
+
+// prefixWriter buffers what is written to it until a whole line can be
+// printed.
+type prefixWriter struct {
+	printer *logPrinter
+	name    string
+
+	mu      sync.Mutex
+	pending []byte
+}
+
+func (w *prefixWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, data...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end == -1 {
+			break
+		}
+
+		w.printer.printLine(w.name, w.pending[:end])
+		w.pending = w.pending[end+1:]
+	}
+
+	return len(data), nil
+}
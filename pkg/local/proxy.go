@@ -0,0 +1,97 @@
+package local
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// proxy forwards a local port to the container of a public service the way
+// the load balancer does once it is deployed: the X-Forwarded headers are
+// set and a 502 is returned while the service can't be reached. The
+// container can be replaced without the local port changing.
+type proxy struct {
+	port   int
+	server *http.Server
+
+	mu     sync.RWMutex
+	target *url.URL
+}
+
+func newProxy(port int) *proxy {
+	p := &proxy{port: port}
+
+	reverseProxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			target := p.currentTarget()
+			r.URL.Scheme = "http"
+			if target != nil {
+				r.URL.Host = target.Host
+			}
+			r.Header.Set("X-Forwarded-Proto", "http")
+			r.Header.Set("X-Forwarded-Port", strconv.Itoa(port))
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "502 Bad Gateway", http.StatusBadGateway)
+		},
+	}
+
+	p.server = &http.Server{
+		Addr: fmt.Sprintf("127.0.0.1:%d", port),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p.currentTarget() == nil {
+				http.Error(w, "503 Service Temporarily Unavailable", http.StatusServiceUnavailable)
+				return
+			}
+
+			reverseProxy.ServeHTTP(w, r)
+		}),
+	}
+
+	return p
+}
+
+// start listens on the local port. Requests are answered with a 503 until
+// a target is set.
+func (p *proxy) start() error {
+	listener, err := net.Listen("tcp", p.server.Addr)
+	if err != nil {
+		return fmt.Errorf("couldn't listen on port %d: %w", p.port, err)
+	}
+
+	go p.server.Serve(listener)
+	return nil
+}
+
+func (p *proxy) stop() error {
+	return p.server.Close()
+}
+
+// setTarget points the proxy at hostAddress, empty while the service is
+// being replaced.
+func (p *proxy) setTarget(hostAddress string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if hostAddress == "" {
+		p.target = nil
+		return
+	}
+
+	p.target = &url.URL{Scheme: "http", Host: hostAddress}
+}
+
+func (p *proxy) currentTarget() *url.URL {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.target
+}
+
+func (p *proxy) url() string {
+	return fmt.Sprintf("http://localhost:%d", p.port)
+}
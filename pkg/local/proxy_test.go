@@ -0,0 +1,62 @@
+package local
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	var forwarded http.Header
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header
+		w.Write([]byte("hello from " + r.URL.Path))
+	}))
+	defer service.Close()
+
+	serviceURL, err := url.Parse(service.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An address nothing listens on, like a container that is starting.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable := listener.Addr().String()
+	listener.Close()
+
+	p := newProxy(8080)
+	request := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		p.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost:8080/status", nil))
+		return recorder
+	}
+
+	if code := request().Code; code != http.StatusServiceUnavailable {
+		t.Errorf("got %d without a target, want 503", code)
+	}
+
+	p.setTarget(unreachable)
+	if code := request().Code; code != http.StatusBadGateway {
+		t.Errorf("got %d with a target that can't be reached, want 502", code)
+	}
+
+	p.setTarget(serviceURL.Host)
+	response := request()
+	if response.Code != http.StatusOK || response.Body.String() != "hello from /status" {
+		t.Errorf("got %d %q from the service", response.Code, response.Body.String())
+	}
+	if forwarded.Get("X-Forwarded-Proto") != "http" || forwarded.Get("X-Forwarded-Port") != "8080" || forwarded.Get("X-Forwarded-For") == "" {
+		t.Errorf("the service got the headers %v, expected the X-Forwarded headers of the load balancer", forwarded)
+	}
+
+	// The service is being replaced.
+	p.setTarget("")
+	if code := request().Code; code != http.StatusServiceUnavailable {
+		t.Errorf("got %d after the target was removed, want 503", code)
+	}
+}
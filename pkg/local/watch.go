@@ -0,0 +1,216 @@
+package local
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+// rebuildDelay is how long a build context has to stay unchanged before the
+// service is rebuilt, so saving many files at once rebuilds it only once.
+const rebuildDelay = 300 * time.Millisecond
+
+// watchedContext is the build context of a service being watched.
+type watchedContext struct {
+	service   string
+	directory string
+	ignored   *utils.DockerIgnore
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	changed string
+	pending chan string
+}
+
+// Watch rebuilds and restarts a service whenever a file in its build
+// context changes, until ctx is done. Files matched by the .dockerignore
+// of the build context are left out, like they are from the build.
+func (e *Environment) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	projectDirectory, err := e.Config.ProjectDirectory()
+	if err != nil {
+		return err
+	}
+
+	var contexts []*watchedContext
+	for _, service := range e.services {
+		watched := &watchedContext{
+			service:   service.config.Name,
+			directory: filepath.Join(projectDirectory, service.config.PathToDockerfile),
+			pending:   make(chan string, 1),
+		}
+		watched.loadIgnoreFile()
+
+		err = watched.addDirectory(watcher, watched.directory)
+		if err != nil {
+			return err
+		}
+
+		contexts = append(contexts, watched)
+	}
+
+	// Each service is rebuilt by its own goroutine so a slow build doesn't
+	// hold up the others, and changes during a build rebuild it once more.
+	var rebuilds sync.WaitGroup
+	for _, watched := range contexts {
+		rebuilds.Add(1)
+		go func(watched *watchedContext) {
+			defer rebuilds.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case changed := <-watched.pending:
+					// A failed build is shown in the service's status, the
+					// next change tries again.
+					e.Rebuild(ctx, watched.service, changed+" changed")
+				}
+			}
+		}(watched)
+	}
+	defer rebuilds.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			if err != nil {
+				return err
+			}
+		case event := <-watcher.Events:
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			for _, watched := range contexts {
+				relativePath, ok := watched.relativePath(event.Name)
+				if !ok {
+					continue
+				}
+
+				if relativePath == ".dockerignore" {
+					watched.loadIgnoreFile()
+				} else if watched.isIgnored(relativePath) {
+					continue
+				}
+
+				if event.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						watched.addDirectory(watcher, event.Name)
+					}
+				}
+
+				watched.changedFile(relativePath)
+			}
+		}
+	}
+}
+
+// loadIgnoreFile reads the .dockerignore of the build context, if there is
+// one. A .dockerignore that can't be read ignores nothing.
+func (w *watchedContext) loadIgnoreFile() {
+	ignored, err := utils.LoadDockerIgnore(w.directory)
+	if err != nil {
+		ignored = nil
+	}
+
+	w.mu.Lock()
+	w.ignored = ignored
+	w.mu.Unlock()
+}
+
+// relativePath returns filePath relative to the build context, false if it
+// isn't in it.
+func (w *watchedContext) relativePath(filePath string) (string, bool) {
+	relativePath, err := filepath.Rel(w.directory, filePath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filepath.ToSlash(relativePath), true
+}
+
+func (w *watchedContext) isIgnored(relativePath string) bool {
+	if isGitPath(relativePath) {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.ignored.Ignored(relativePath)
+}
+
+// skipDirectory reports whether nothing in the directory at relativePath
+// needs to be watched.
+func (w *watchedContext) skipDirectory(relativePath string) bool {
+	if isGitPath(relativePath) {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.ignored.SkipDirectory(relativePath)
+}
+
+func isGitPath(relativePath string) bool {
+	return relativePath == ".git" || strings.HasPrefix(relativePath, ".git/")
+}
+
+// addDirectory watches directory and the directories in it that aren't
+// ignored.
+func (w *watchedContext) addDirectory(watcher *fsnotify.Watcher, directory string) error {
+	return filepath.WalkDir(directory, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if relativePath, ok := w.relativePath(filePath); ok && relativePath != "." && w.skipDirectory(relativePath) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(filePath)
+	})
+}
+
+// changedFile schedules a rebuild once the build context has stayed
+// unchanged for rebuildDelay.
+func (w *watchedContext) changedFile(relativePath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.changed = relativePath
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	w.timer = time.AfterFunc(rebuildDelay, func() {
+		w.mu.Lock()
+		changed := w.changed
+		w.mu.Unlock()
+
+		select {
+		case w.pending <- changed:
+		default:
+			// A rebuild is already waiting, it will pick up this change.
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestWatchedContext(t *testing.T, dockerignore string) *watchedContext {
+	t.Helper()
+
+	dir := t.TempDir()
+	if dockerignore != "" {
+		err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(dockerignore), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watched := &watchedContext{
+		service:   "api",
+		directory: dir,
+		pending:   make(chan string, 1),
+	}
+	watched.loadIgnoreFile()
+
+	return watched
+}
+
+func TestWatchedContextDebounce(t *testing.T) {
+	watched := newTestWatchedContext(t, "")
+
+	// Saving many files at once rebuilds once, naming the last change.
+	for _, changed := range []string{"a.go", "b.go", "c.go"} {
+		watched.changedFile(changed)
+		time.Sleep(rebuildDelay / 10)
+	}
+
+	select {
+	case changed := <-watched.pending:
+		t.Fatalf("a rebuild for %s was scheduled before the changes settled", changed)
+	case <-time.After(rebuildDelay / 2):
+	}
+
+	select {
+	case changed := <-watched.pending:
+		if changed != "c.go" {
+			t.Errorf("the rebuild is for %s, want c.go", changed)
+		}
+	case <-time.After(rebuildDelay * 3):
+		t.Fatal("no rebuild was scheduled")
+	}
+
+	select {
+	case changed := <-watched.pending:
+		t.Errorf("a second rebuild was scheduled for %s", changed)
+	case <-time.After(rebuildDelay * 2):
+	}
+}
+
+func TestWatchedContextIgnored(t *testing.T) {
+	watched := newTestWatchedContext(t, "node_modules\n*.log\n!keep.log\n")
+
+	tests := map[string]bool{
+		"main.go":                  false,
+		"node_modules/left-pad.js": true,
+		"debug.log":                true,
+		"keep.log":                 false,
+		".git/HEAD":                true,
+		".gitignore":               false,
+		"Dockerfile":               false,
+	}
+	for relativePath, want := range tests {
+		if got := watched.isIgnored(relativePath); got != want {
+			t.Errorf("isIgnored(%q) = %v, want %v", relativePath, got, want)
+		}
+	}
+
+	if !watched.skipDirectory(".git") {
+		t.Errorf("the .git directory is watched")
+	}
+
+	// A changed .dockerignore is picked up.
+	err := os.WriteFile(filepath.Join(watched.directory, ".dockerignore"), []byte("*.go\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watched.loadIgnoreFile()
+	if !watched.isIgnored("main.go") || watched.isIgnored("debug.log") {
+		t.Errorf("the new .dockerignore isn't used")
+	}
+}
+
+func TestWatchedContextRelativePath(t *testing.T) {
+	watched := newTestWatchedContext(t, "")
+
+	relativePath, ok := watched.relativePath(filepath.Join(watched.directory, "src", "main.go"))
+	if !ok || relativePath != "src/main.go" {
+		t.Errorf("got %q, %v for a file in the build context", relativePath, ok)
+	}
+
+	if _, ok := watched.relativePath(filepath.Join(filepath.Dir(watched.directory), "other", "main.go")); ok {
+		t.Errorf("a file outside the build context is in it")
+	}
+}
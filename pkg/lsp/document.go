@@ -0,0 +1,234 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// document is a config file open in the editor.
+type document struct {
+	uri  string
+	path string
+	text string
+	// columns is what the columns of the nodes count.
+	columns columnUnit
+
+	// root is the parsed document, nil when the text doesn't parse.
+	root *yaml.Node
+	// config is the last version of the document that parsed successfully,
+	// it is kept around so completions work while the file is being edited.
+	config *jacuik_config.AppConfig
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{
+		uri:     uri,
+		path:    uriToPath(uri),
+		columns: unitRunes,
+	}
+
+	// yaml.v3 counts the runes before a node, the TOML parser the bytes.
+	if filepath.Ext(d.path) == ".toml" {
+		d.columns = unitBytes
+	}
+	d.update(text)
+
+	return d
+}
+
+func (d *document) update(text string) {
+	d.text = text
+	d.root = nil
+
+	// Every file type is parsed into the same yaml.v3 node tree.
+	root, err := jacuik_config.ParseDocument(d.path, []byte(text))
+	if err != nil || len(root.Content) == 0 {
+		return
+	}
+	d.root = root
+
+	var config jacuik_config.AppConfig
+	err = root.Decode(&config)
+	if err == nil {
+		d.config = &config
+	}
+}
+
+func (d *document) lines() []string {
+	return strings.Split(d.text, "\n")
+}
+
+// columnUnit is what a column within a line counts.
+type columnUnit int
+
+const (
+	unitBytes columnUnit = iota
+	unitRunes
+	// unitUTF16 is what LSP positions count.
+	unitUTF16
+)
+
+// convertColumn converts a zero based column of line from one unit to
+// another. Columns past the end of the line are kept past the end.
+func convertColumn(line string, column int, from, to columnUnit) int {
+	var offsets [3]int
+	for offsets[unitBytes] < len(line) && offsets[from] < column {
+		r, width := utf8.DecodeRuneInString(line[offsets[unitBytes]:])
+		offsets[unitBytes] += width
+		offsets[unitRunes]++
+		offsets[unitUTF16]++
+		if r >= 0x10000 {
+			offsets[unitUTF16]++
+		}
+	}
+
+	return offsets[to] + column - offsets[from]
+}
+
+// lineColumn converts a zero based column of a line of the document from
+// one unit to another.
+func (d *document) lineColumn(line, column int, from, to columnUnit) int {
+	lines := d.lines()
+	if line < 0 || line >= len(lines) {
+		return column
+	}
+
+	return convertColumn(lines[line], column, from, to)
+}
+
+// position returns the LSP position of a zero based line and node column.
+func (d *document) position(line, column int) position {
+	return position{Line: line, Character: d.lineColumn(line, column, d.columns, unitUTF16)}
+}
+
+// cursorContext describes the node under the cursor.
+type cursorContext struct {
+	// path is the list of keys leading to the node, without list indexes.
+	path  []string
+	node  *yaml.Node
+	isKey bool
+	// service is the service mapping containing the node, if any.
+	service *yaml.Node
+}
+
+// nodeAt returns the scalar node at a zero based position.
+func (d *document) nodeAt(pos position) *cursorContext {
+	if d.root == nil {
+		return nil
+	}
+
+	column := d.lineColumn(pos.Line, pos.Character, unitUTF16, d.columns)
+	return d.findNode(d.root, nil, nil, pos.Line+1, column+1)
+}
+
+func (d *document) findNode(node *yaml.Node, path []string, service *yaml.Node, line, column int) *cursorContext {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if result := d.findNode(child, path, service, line, column); result != nil {
+				return result
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			value := node.Content[i+1]
+			keyPath := append(append([]string{}, path...), key.Value)
+
+			if d.containsPosition(key, line, column) {
+				return &cursorContext{path: keyPath, node: key, isKey: true, service: service}
+			}
+
+			if result := d.findNode(value, keyPath, service, line, column); result != nil {
+				return result
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			itemService := service
+			if len(path) == 1 && path[0] == "services" && item.Kind == yaml.MappingNode {
+				itemService = item
+			}
+
+			if result := d.findNode(item, path, itemService, line, column); result != nil {
+				return result
+			}
+		}
+	case yaml.ScalarNode:
+		if d.containsPosition(node, line, column) {
+			return &cursorContext{path: path, node: node, service: service}
+		}
+	}
+
+	return nil
+}
+
+// scalarWidth returns the width of a scalar in the unit of the node
+// columns.
+func (d *document) scalarWidth(node *yaml.Node) int {
+	width := len(node.Value)
+	if d.columns == unitRunes {
+		width = utf8.RuneCountInString(node.Value)
+	}
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		width += 2
+	}
+
+	return width
+}
+
+func (d *document) containsPosition(node *yaml.Node, line, column int) bool {
+	return node.Kind == yaml.ScalarNode &&
+		node.Line == line &&
+		column >= node.Column &&
+		column <= node.Column+d.scalarWidth(node)
+}
+
+func (d *document) nodeRange(node *yaml.Node) lspRange {
+	return lspRange{
+		Start: d.position(node.Line-1, node.Column-1),
+		End:   d.position(node.Line-1, node.Column-1+d.scalarWidth(node)),
+	}
+}
+
+// mappingValue returns the value of key in a mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+
+	return filepath.FromSlash(parsed.Path)
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func isConfigFile(path string) bool {
+	switch filepath.Base(path) {
+	case "schema.yaml", "schema.yml", "schema.json", "schema.toml":
+		return true
+	default:
+		return false
+	}
+}
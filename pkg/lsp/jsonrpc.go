@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+const jsonRPCVersion = "2.0"
+
+// JSON-RPC error codes used by the server.
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+// response is a successful reply. Its result is always sent, a null result
+// is how some requests, like shutdown, succeed.
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+// errorResponse is a failed reply, it must not have a result.
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   responseError    `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// conn reads and writes JSON-RPC messages framed with a Content-Length
+// header, as described by the Language Server Protocol.
+type conn struct {
+	reader *textproto.Reader
+	writer io.Writer
+	mu     sync.Mutex
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		reader: textproto.NewReader(bufio.NewReader(r)),
+		writer: w,
+	}
+}
+
+func (c *conn) read() (*request, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Content-Length header: %w", err)
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.reader.R, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var req request
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func (c *conn) write(message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (c *conn) reply(id *json.RawMessage, result interface{}) error {
+	return c.write(response{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+		Result:  result,
+	})
+}
+
+func (c *conn) replyError(id *json.RawMessage, code int, message string) error {
+	return c.write(errorResponse{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+		Error: responseError{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+func (c *conn) notify(method string, params interface{}) error {
+	return c.write(notification{
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+}
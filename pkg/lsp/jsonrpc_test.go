@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// frame returns body framed as an LSP message.
+func frame(body string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func TestConnRead(t *testing.T) {
+	// The optional Content-Type header is ignored, and the body is read by
+	// its length rather than up to the next header.
+	input := frame(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"file:///tmp/Content-Length"}}`) +
+		"Content-Type: application/vscode-jsonrpc; charset=utf-8\r\n" + frame(`{"jsonrpc":"2.0","method":"initialized"}`)
+
+	c := newConn(strings.NewReader(input), io.Discard)
+
+	first, err := c.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Method != "initialize" || first.ID == nil || string(*first.ID) != "1" {
+		t.Errorf("the first message is %s with the id %v, expected initialize with the id 1", first.Method, first.ID)
+	}
+
+	second, err := c.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Method != "initialized" || second.ID != nil {
+		t.Errorf("the second message is %s with the id %v, expected the initialized notification", second.Method, second.ID)
+	}
+
+	if _, err := c.read(); err != io.EOF {
+		t.Errorf("got %v after the last message, expected io.EOF", err)
+	}
+}
+
+func TestConnReadInvalidLength(t *testing.T) {
+	c := newConn(strings.NewReader("Content-Length: many\r\n\r\n{}"), io.Discard)
+
+	_, err := c.read()
+	if err == nil || !strings.HasPrefix(err.Error(), "Invalid Content-Length header") {
+		t.Errorf("got %v, expected an invalid Content-Length error", err)
+	}
+}
+
+func TestConnWrite(t *testing.T) {
+	var output bytes.Buffer
+	c := newConn(strings.NewReader(""), &output)
+
+	id := json.RawMessage("7")
+	if err := c.reply(&id, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.replyError(&id, codeMethodNotFound, "nope"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A null result is still sent, and an error has no result.
+	want := "Content-Length: 38\r\n\r\n" + `{"jsonrpc":"2.0","id":7,"result":null}` +
+		"Content-Length: 65\r\n\r\n" + `{"jsonrpc":"2.0","id":7,"error":{"code":-32601,"message":"nope"}}`
+	if output.String() != want {
+		t.Errorf("got:\n%q\nwant:\n%q", output.String(), want)
+	}
+}
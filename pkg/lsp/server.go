@@ -0,0 +1,382 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+var (
+	// A YAML flow sequence that is still open, e.g. `dependsOn: [db, `.
+	yamlInlineDependsOnPattern = regexp.MustCompile(`dependsOn:\s*\[[^\]]*$`)
+	// A JSON array that is still open, e.g. `"dependsOn": ["db", `.
+	jsonDependsOnPattern = regexp.MustCompile(`"dependsOn"\s*:\s*\[[^\]]*$`)
+)
+
+// Server is a language server for Jacuik config files.
+type Server struct {
+	conn      *conn
+	documents map[string]*document
+	shutdown  bool
+}
+
+// NewServer creates a language server that communicates over r and w.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		conn:      newConn(r, w),
+		documents: make(map[string]*document),
+	}
+}
+
+// Run handles messages until the client asks the server to exit.
+func (s *Server) Run() error {
+	for {
+		req, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("Exit requested before shutdown.")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.handle(req)
+
+		// Notifications don't get a response.
+		if req.ID == nil {
+			continue
+		}
+
+		if rpcErr != nil {
+			err = s.conn.replyError(req.ID, rpcErr.Code, rpcErr.Message)
+		} else {
+			err = s.conn.reply(req.ID, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (interface{}, *responseError) {
+	switch req.Method {
+	case "initialize":
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   1,
+				HoverProvider:      true,
+				DefinitionProvider: true,
+				CompletionProvider: &completionOptions{
+					TriggerCharacters: []string{"-", " ", "[", "\"", ","},
+				},
+			},
+			ServerInfo: serverInfo{Name: "jacuik"},
+		}, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		doc := newDocument(params.TextDocument.URI, params.TextDocument.Text)
+		s.documents[doc.uri] = doc
+		s.publishDiagnostics(doc)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params didChangeTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok || len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+
+		// The server asks for full document sync so the last change is
+		// the whole document.
+		doc.update(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		s.publishDiagnostics(doc)
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params didCloseTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+		s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/hover":
+		doc, pos, rpcErr := s.documentPosition(req)
+		if rpcErr != nil || doc == nil {
+			return nil, rpcErr
+		}
+
+		return s.hover(doc, pos), nil
+
+	case "textDocument/definition":
+		doc, pos, rpcErr := s.documentPosition(req)
+		if rpcErr != nil || doc == nil {
+			return nil, rpcErr
+		}
+
+		return s.definition(doc, pos), nil
+
+	case "textDocument/completion":
+		doc, pos, rpcErr := s.documentPosition(req)
+		if rpcErr != nil || doc == nil {
+			return []completionItem{}, rpcErr
+		}
+
+		return s.completion(doc, pos), nil
+
+	case "initialized", "$/cancelRequest", "$/setTrace", "textDocument/didSave":
+		return nil, nil
+
+	default:
+		return nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("Method [%s] is not supported.", req.Method),
+		}
+	}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func (s *Server) documentPosition(req *request) (*document, position, *responseError) {
+	var params textDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, position{}, invalidParams(err)
+	}
+
+	return s.documents[params.TextDocument.URI], params.Position, nil
+}
+
+func (s *Server) publishDiagnostics(doc *document) {
+	diagnostics := []diagnostic{}
+
+	if isConfigFile(doc.path) {
+		lines := doc.lines()
+		for _, validationError := range jacuik_config.ValidateConfig(doc.path, []byte(doc.text)) {
+			// Problems in included files are shown at the top of the
+			// document with the location they were found at.
+			if validationError.File != filepath.Base(doc.path) {
+				diagnostics = append(diagnostics, diagnostic{
+					Range:    lspRange{End: position{Character: 1}},
+					Severity: diagnosticSeverityError,
+					Source:   "jacuik",
+					Message:  validationError.Error(),
+				})
+				continue
+			}
+
+			start := position{}
+			if validationError.Line > 0 {
+				start = doc.position(validationError.Line-1, validationError.Column-1)
+			}
+
+			// Highlight to the end of the line the problem was found on.
+			end := start
+			if start.Line < len(lines) {
+				line := strings.TrimRight(lines[start.Line], " \r")
+				end.Character = convertColumn(line, len(line), unitBytes, unitUTF16)
+			}
+			if end.Character <= start.Character {
+				end.Character = start.Character + 1
+			}
+
+			diagnostics = append(diagnostics, diagnostic{
+				Range:    lspRange{Start: start, End: end},
+				Severity: diagnosticSeverityError,
+				Source:   "jacuik",
+				Message:  validationError.Message,
+			})
+		}
+	}
+
+	s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         doc.uri,
+		Diagnostics: diagnostics,
+	})
+}
+
+func (s *Server) hover(doc *document, pos position) *hover {
+	cursor := doc.nodeAt(pos)
+	if cursor == nil {
+		return nil
+	}
+
+	fieldDoc, ok := jacuik_config.LookupFieldDoc(cursor.path)
+	if !ok {
+		return nil
+	}
+
+	contents := strings.Builder{}
+	contents.WriteString(fmt.Sprintf("**%s** `%s`", fieldDoc.Name, fieldDoc.Type))
+	if fieldDoc.Required {
+		contents.WriteString(" (required)")
+	}
+	if fieldDoc.Description != "" {
+		contents.WriteString("\n\n" + fieldDoc.Description)
+	}
+	if len(fieldDoc.Enum) > 0 {
+		contents.WriteString(fmt.Sprintf("\n\nOne of: `%s`", strings.Join(fieldDoc.Enum, "`, `")))
+	}
+	if fieldDoc.Default != "" {
+		contents.WriteString(fmt.Sprintf("\n\nDefault: `%s`", fieldDoc.Default))
+	}
+
+	hoverRange := doc.nodeRange(cursor.node)
+	return &hover{
+		Contents: markupContent{Kind: markupKindMarkdown, Value: contents.String()},
+		Range:    &hoverRange,
+	}
+}
+
+// definition jumps from a dependency to the service or resource it names,
+// and from anywhere else in a service entry to the service's Dockerfile.
+func (s *Server) definition(doc *document, pos position) *location {
+	cursor := doc.nodeAt(pos)
+	if cursor == nil {
+		return nil
+	}
+
+	if !cursor.isKey && strings.Join(cursor.path, ".") == "services.dependsOn" {
+		for _, listName := range []string{"services", "resources"} {
+			list := mappingValue(doc.root.Content[0], listName)
+			if list == nil {
+				continue
+			}
+
+			for _, item := range list.Content {
+				name := mappingValue(item, "name")
+				if name != nil && name.Value == cursor.node.Value {
+					return &location{URI: doc.uri, Range: doc.nodeRange(name)}
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if cursor.service == nil {
+		return nil
+	}
+
+	servicePath := mappingValue(cursor.service, "path")
+	if servicePath == nil {
+		return nil
+	}
+
+	dockerfilePath := filepath.Join(filepath.Dir(doc.path), servicePath.Value, "Dockerfile")
+	if _, err := os.Stat(dockerfilePath); err != nil {
+		return nil
+	}
+
+	return &location{URI: pathToURI(dockerfilePath)}
+}
+
+// completion offers the names of services and resources when the cursor is
+// inside a dependsOn list.
+func (s *Server) completion(doc *document, pos position) []completionItem {
+	items := []completionItem{}
+	if doc.config == nil || !inDependsOn(doc, pos) {
+		return items
+	}
+
+	for _, svc := range doc.config.Services {
+		items = append(items, completionItem{
+			Label:  svc.Name,
+			Kind:   completionItemKindValue,
+			Detail: fmt.Sprintf("%s service", svc.GetKind()),
+		})
+	}
+
+	for _, resource := range doc.config.Resources {
+		items = append(items, completionItem{
+			Label:  resource.Name,
+			Kind:   completionItemKindValue,
+			Detail: fmt.Sprintf("%s resource", resource.Type),
+		})
+	}
+
+	return items
+}
+
+func inDependsOn(doc *document, pos position) bool {
+	lines := doc.lines()
+	if pos.Line >= len(lines) {
+		return false
+	}
+
+	currentLine := lines[pos.Line]
+	if offset := convertColumn(currentLine, pos.Character, unitUTF16, unitBytes); offset < len(currentLine) {
+		currentLine = currentLine[:offset]
+	}
+
+	if strings.HasSuffix(doc.path, ".json") {
+		before := strings.Join(append(append([]string{}, lines[:pos.Line]...), currentLine), "\n")
+		return jsonDependsOnPattern.MatchString(before)
+	}
+
+	if yamlInlineDependsOnPattern.MatchString(currentLine) {
+		return true
+	}
+
+	// A block list item, look for the key the list belongs to on the
+	// closest line above that is indented less.
+	trimmed := strings.TrimLeft(currentLine, " ")
+	if !strings.HasPrefix(trimmed, "-") {
+		return false
+	}
+
+	indent := len(currentLine) - len(trimmed)
+	for i := pos.Line - 1; i >= 0; i-- {
+		line := lines[i]
+		lineTrimmed := strings.TrimLeft(line, " ")
+		if lineTrimmed == "" || strings.HasPrefix(lineTrimmed, "#") {
+			continue
+		}
+
+		if len(line)-len(lineTrimmed) < indent {
+			return strings.HasPrefix(strings.TrimPrefix(lineTrimmed, "- "), "dependsOn:")
+		}
+
+		// Other items of the same list.
+		if len(line)-len(lineTrimmed) == indent && strings.HasPrefix(lineTrimmed, "-") {
+			continue
+		}
+
+		if len(line)-len(lineTrimmed) == indent {
+			return strings.HasPrefix(lineTrimmed, "dependsOn:")
+		}
+	}
+
+	return false
+}
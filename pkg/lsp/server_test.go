@@ -0,0 +1,271 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testConfig has an emoji, two UTF-16 code units, before positions on its
+// dependsOn line.
+const testConfig = `version: 2
+name: demo
+services:
+  - name: api
+    path: ./api
+    dependsOn: ["🚀", db]
+resources:
+  - name: db
+    type: postgres
+`
+
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// session runs a server in a project with an api service, sends it the
+// messages then shuts it down, and returns what it sent back.
+func session(t *testing.T, messages ...map[string]interface{}) (string, []testMessage) {
+	t.Helper()
+
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "api"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "api", "Dockerfile"), []byte("FROM scratch\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(filepath.Join(dir, "schema.yaml"))
+
+	var input bytes.Buffer
+	messages = append(messages, map[string]interface{}{"id": 1000, "method": "shutdown"}, map[string]interface{}{"method": "exit"})
+	for _, message := range messages {
+		message["jsonrpc"] = jsonRPCVersion
+		body, err := json.Marshal(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input.WriteString(frame(strings.ReplaceAll(string(body), "$URI", uri)))
+	}
+
+	var output bytes.Buffer
+	err = NewServer(&input, &output).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(&output))
+	var result []testMessage
+	for {
+		header, err := reader.ReadMIMEHeader()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body := make([]byte, length)
+		_, err = io.ReadFull(reader.R, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var message testMessage
+		err = json.Unmarshal(body, &message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, message)
+	}
+
+	return uri, result
+}
+
+func openConfig(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"method": "textDocument/didOpen",
+		"params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": "$URI", "text": text},
+		},
+	}
+}
+
+func positionRequest(id int, method string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     id,
+		"method": method,
+		"params": map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": "$URI"},
+			"position":     map[string]interface{}{"line": line, "character": character},
+		},
+	}
+}
+
+// reply returns the reply to the request with the given id.
+func reply(t *testing.T, messages []testMessage, id int) testMessage {
+	t.Helper()
+
+	for _, message := range messages {
+		if message.ID != nil && *message.ID == id && message.Method == "" {
+			return message
+		}
+	}
+
+	t.Fatalf("no reply to the request %d", id)
+	return testMessage{}
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	uri, messages := session(t, openConfig(testConfig))
+
+	var params publishDiagnosticsParams
+	for _, message := range messages {
+		if message.Method == "textDocument/publishDiagnostics" {
+			if err := json.Unmarshal(message.Params, &params); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if params.URI != uri {
+		t.Fatalf("the diagnostics are for %q, expected %q", params.URI, uri)
+	}
+	if len(params.Diagnostics) != 1 {
+		t.Fatalf("got the diagnostics %+v, expected one for the unknown dependency", params.Diagnostics)
+	}
+
+	got := params.Diagnostics[0]
+	if got.Message != `"🚀" is not a service or resource in the project` {
+		t.Errorf("got the message %q", got.Message)
+	}
+
+	// The line is 24 runes long, the emoji is two UTF-16 code units.
+	want := lspRange{Start: position{Line: 5, Character: 16}, End: position{Line: 5, Character: 25}}
+	if got.Range != want {
+		t.Errorf("the diagnostic is at %+v, expected %+v", got.Range, want)
+	}
+}
+
+func TestServerHover(t *testing.T) {
+	_, messages := session(t,
+		openConfig(testConfig),
+		// db starts at rune 21 of its line, after the emoji that is 22.
+		positionRequest(1, "textDocument/hover", 5, 22),
+		positionRequest(2, "textDocument/hover", 4, 5),
+		positionRequest(3, "textDocument/hover", 3, 0),
+	)
+
+	var dependency hover
+	if err := json.Unmarshal(reply(t, messages, 1).Result, &dependency); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dependency.Contents.Value, "**dependsOn**") {
+		t.Errorf("the hover of db is %q, expected the docs of dependsOn", dependency.Contents.Value)
+	}
+	want := lspRange{Start: position{Line: 5, Character: 22}, End: position{Line: 5, Character: 24}}
+	if dependency.Range == nil || *dependency.Range != want {
+		t.Errorf("the hover of db is at %+v, expected %+v", dependency.Range, want)
+	}
+
+	var path hover
+	if err := json.Unmarshal(reply(t, messages, 2).Result, &path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(path.Contents.Value, "**path**") || !strings.Contains(path.Contents.Value, "(required)") {
+		t.Errorf("the hover of path is %q, expected the docs of a required field", path.Contents.Value)
+	}
+
+	// The indentation before a list item.
+	if result := string(reply(t, messages, 3).Result); result != "null" {
+		t.Errorf("the hover outside any field is %s, expected null", result)
+	}
+}
+
+func TestServerCompletion(t *testing.T) {
+	editing := strings.Replace(testConfig, `dependsOn: ["🚀", db]`, `dependsOn: ["🚀", `, 1)
+
+	_, messages := session(t,
+		openConfig(testConfig),
+		// The config doesn't parse while it is edited, the names are
+		// completed from the last version that did.
+		map[string]interface{}{
+			"method": "textDocument/didChange",
+			"params": map[string]interface{}{
+				"textDocument":   map[string]interface{}{"uri": "$URI"},
+				"contentChanges": []map[string]interface{}{{"text": editing}},
+			},
+		},
+		positionRequest(1, "textDocument/completion", 5, 22),
+		positionRequest(2, "textDocument/completion", 4, 10),
+	)
+
+	var items []completionItem
+	if err := json.Unmarshal(reply(t, messages, 1).Result, &items); err != nil {
+		t.Fatal(err)
+	}
+
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label+" "+item.Detail)
+	}
+	if strings.Join(labels, ", ") != "api web service, db postgres resource" {
+		t.Errorf("got the completions %v, expected the services and resources", labels)
+	}
+
+	if result := string(reply(t, messages, 2).Result); result != "[]" {
+		t.Errorf("got the completions %s outside dependsOn, expected none", result)
+	}
+}
+
+func TestServerUnknownMethod(t *testing.T) {
+	_, messages := session(t, map[string]interface{}{"id": 1, "method": "textDocument/rename"})
+
+	got := reply(t, messages, 1).Error
+	if got == nil || got.Code != codeMethodNotFound {
+		t.Errorf("got the error %+v, expected method not found", got)
+	}
+}
+
+func TestConvertColumn(t *testing.T) {
+	line := `a: "é🚀x"`
+
+	tests := []struct {
+		column   int
+		from, to columnUnit
+		want     int
+	}{
+		{column: 5, from: unitRunes, to: unitUTF16, want: 5},
+		{column: 6, from: unitRunes, to: unitUTF16, want: 7},
+		{column: 6, from: unitRunes, to: unitBytes, want: 10},
+		{column: 10, from: unitBytes, to: unitUTF16, want: 7},
+		{column: 7, from: unitUTF16, to: unitRunes, want: 6},
+		// Past the end of the line.
+		{column: 10, from: unitRunes, to: unitUTF16, want: 11},
+	}
+
+	for _, test := range tests {
+		if got := convertColumn(line, test.column, test.from, test.to); got != test.want {
+			t.Errorf("convertColumn(%d, %d, %d) = %d, want %d", test.column, test.from, test.to, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package templates
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zchase/jacuik/pkg/utils"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//go:embed all:starters
+var builtInProjectStarters embed.FS
+
+// StarterData holds the variables available to a starter when it is rendered.
+type StarterData struct {
+	ProjectName string
+	Description string
+	// Ports maps the name of each service in the starter to the port its
+	// template listens on.
+	Ports map[string]int
+}
+
+type starterManifest struct {
+	Description string                   `yaml:"description"`
+	Services    []starterServiceManifest `yaml:"services"`
+}
+
+type starterServiceManifest struct {
+	Name     string `yaml:"name"`
+	Template string `yaml:"template"`
+}
+
+// ProjectStarter is a directory of files, and a set of services, used to
+// create a complete new project.
+type ProjectStarter struct {
+	Name        string
+	Description string
+	Source      string
+
+	services []starterServiceManifest
+	files    fs.FS
+}
+
+// ListProjectStarters returns the built-in project starters.
+func ListProjectStarters() ([]ProjectStarter, error) {
+	builtIn, err := fs.Sub(builtInProjectStarters, "starters")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(builtIn, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ProjectStarter
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		files, err := fs.Sub(builtIn, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		starter, err := loadStarter(entry.Name(), builtInSource, files)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *starter)
+	}
+
+	sort.Slice(result, func(x, y int) bool {
+		return result[x].Name < result[y].Name
+	})
+
+	return result, nil
+}
+
+// GetProjectStarter looks up a starter by name. If nameOrPath points to a
+// directory on disk, such as a git repository that has been checked out,
+// the starter is loaded from that directory instead.
+func GetProjectStarter(nameOrPath string) (*ProjectStarter, error) {
+	info, err := os.Stat(nameOrPath)
+	if err == nil && info.IsDir() {
+		absolutePath, err := filepath.Abs(nameOrPath)
+		if err != nil {
+			return nil, err
+		}
+
+		return loadStarter(filepath.Base(absolutePath), absolutePath, os.DirFS(absolutePath))
+	}
+
+	starters, err := ListProjectStarters()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, s := range starters {
+		if s.Name == nameOrPath {
+			result := s
+			return &result, nil
+		}
+		names = append(names, s.Name)
+	}
+
+	return nil, fmt.Errorf("Unknown project starter [%s]. Available starters: %s. A path to a starter directory may also be supplied.", nameOrPath, strings.Join(names, ", "))
+}
+
+func loadStarter(name, source string, files fs.FS) (*ProjectStarter, error) {
+	var manifest starterManifest
+	manifestContents, err := fs.ReadFile(files, templateManifestFile)
+	if err == nil {
+		err = yaml.Unmarshal(manifestContents, &manifest)
+		if err != nil {
+			return nil, utils.NewErrorMessage(fmt.Sprintf("invalid manifest for starter [%s]", name), err)
+		}
+	}
+
+	return &ProjectStarter{
+		Name:        name,
+		Description: manifest.Description,
+		Source:      source,
+		services:    manifest.Services,
+		files:       files,
+	}, nil
+}
+
+// Render writes the starter into the destination directory. Each service
+// listed in the starter's manifest is scaffolded from its service template,
+// looked up in userTemplateDir and the built-in templates.
+func (p *ProjectStarter) Render(destination, userTemplateDir string, data StarterData) error {
+	if data.Ports == nil {
+		data.Ports = make(map[string]int)
+	}
+
+	serviceTemplates := make(map[string]*ServiceTemplate)
+	for _, svc := range p.services {
+		serviceTemplate, err := GetServiceTemplate(svc.Template, userTemplateDir)
+		if err != nil {
+			return utils.NewErrorMessage(fmt.Sprintf("couldn't load template for service [%s]", svc.Name), err)
+		}
+
+		serviceTemplates[svc.Name] = serviceTemplate
+		data.Ports[svc.Name] = serviceTemplate.Port
+	}
+
+	err := renderDirectory(p.files, destination, data)
+	if err != nil {
+		return err
+	}
+
+	for _, svc := range p.services {
+		serviceTemplate := serviceTemplates[svc.Name]
+
+		serviceDirPath := filepath.Join(destination, svc.Name)
+		err = os.MkdirAll(serviceDirPath, os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		err = serviceTemplate.Render(serviceDirPath, TemplateData{
+			ServiceName: svc.Name,
+			ProjectName: data.ProjectName,
+			Port:        serviceTemplate.Port,
+		})
+		if err != nil {
+			return utils.NewErrorMessage(fmt.Sprintf("couldn't render service [%s]", svc.Name), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+	"github.com/zchase/jacuik/pkg/utils"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// The all: prefix is needed so that dotfiles like .dockerignore are embedded.
+//
+//go:embed all:services
+var builtInServiceTemplates embed.FS
+
+const (
+	// DefaultUserTemplateDirectory is where user defined templates are looked
+	// up, relative to the project root, when no directory is supplied.
+	DefaultUserTemplateDirectory = ".jacuik/templates"
+
+	templateManifestFile = "template.yaml"
+	templateFileSuffix   = ".tmpl"
+	builtInSource        = "built-in"
+)
+
+// TemplateData holds the variables available to a template when it
+// is rendered.
+type TemplateData struct {
+	ServiceName string
+	ProjectName string
+	Port        int
+}
+
+type templateManifest struct {
+	Description string `yaml:"description"`
+	Kind        string `yaml:"kind"`
+	Port        int    `yaml:"port"`
+}
+
+// ServiceTemplate is a directory of files used to scaffold a new service.
+type ServiceTemplate struct {
+	Name        string
+	Description string
+	// Kind is the kind of service the template creates, empty for web
+	// services.
+	Kind string
+	// Port is 0 for workers, they don't listen on one.
+	Port   int
+	Source string
+
+	files fs.FS
+}
+
+// ListServiceTemplates returns the built-in templates along with any
+// templates found in userTemplateDir. User templates take precedence over
+// built-in templates with the same name.
+func ListServiceTemplates(userTemplateDir string) ([]ServiceTemplate, error) {
+	templatesByName := make(map[string]ServiceTemplate)
+
+	builtIn, err := fs.Sub(builtInServiceTemplates, "services")
+	if err != nil {
+		return nil, err
+	}
+
+	builtInTemplates, err := loadTemplates(builtIn, builtInSource)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range builtInTemplates {
+		templatesByName[t.Name] = t
+	}
+
+	if userTemplateDir != "" {
+		info, err := os.Stat(userTemplateDir)
+		if err == nil && info.IsDir() {
+			userTemplates, err := loadTemplates(os.DirFS(userTemplateDir), userTemplateDir)
+			if err != nil {
+				return nil, err
+			}
+			for _, t := range userTemplates {
+				templatesByName[t.Name] = t
+			}
+		} else if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	var result []ServiceTemplate
+	for _, t := range templatesByName {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(x, y int) bool {
+		return result[x].Name < result[y].Name
+	})
+
+	return result, nil
+}
+
+// GetServiceTemplate looks up a single template by name.
+func GetServiceTemplate(name, userTemplateDir string) (*ServiceTemplate, error) {
+	serviceTemplates, err := ListServiceTemplates(userTemplateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, t := range serviceTemplates {
+		if t.Name == name {
+			result := t
+			return &result, nil
+		}
+		names = append(names, t.Name)
+	}
+
+	return nil, fmt.Errorf("Unknown service template [%s]. Available templates: %s.", name, strings.Join(names, ", "))
+}
+
+func loadTemplates(root fs.FS, source string) ([]ServiceTemplate, error) {
+	entries, err := fs.ReadDir(root, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ServiceTemplate
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		files, err := fs.Sub(root, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		manifest := templateManifest{Port: jacuik_config.DefaultServicePort}
+		manifestContents, err := fs.ReadFile(files, templateManifestFile)
+		if err == nil {
+			err = yaml.Unmarshal(manifestContents, &manifest)
+			if err != nil {
+				return nil, utils.NewErrorMessage(fmt.Sprintf("invalid manifest for template [%s]", entry.Name()), err)
+			}
+		}
+
+		switch manifest.Kind {
+		case "", jacuik_config.ServiceKindWeb:
+			if manifest.Port == 0 {
+				manifest.Port = jacuik_config.DefaultServicePort
+			}
+		case jacuik_config.ServiceKindWorker:
+			manifest.Port = 0
+		default:
+			return nil, fmt.Errorf("The kind of template [%s] must be %s or %s, got [%s].", entry.Name(), jacuik_config.ServiceKindWeb, jacuik_config.ServiceKindWorker, manifest.Kind)
+		}
+
+		result = append(result, ServiceTemplate{
+			Name:        entry.Name(),
+			Description: manifest.Description,
+			Kind:        manifest.Kind,
+			Port:        manifest.Port,
+			Source:      source,
+			files:       files,
+		})
+	}
+
+	return result, nil
+}
+
+// Render writes the template into the destination directory.
+func (t *ServiceTemplate) Render(destination string, data TemplateData) error {
+	return renderDirectory(t.files, destination, data)
+}
+
+// renderDirectory writes the contents of files into the destination directory.
+// Files ending in .tmpl are rendered with text/template and have the suffix
+// removed, all other files are copied as is.
+func renderDirectory(files fs.FS, destination string, data interface{}) error {
+	return fs.WalkDir(files, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if filePath == "." || filePath == templateManifestFile {
+			return nil
+		}
+
+		// Starters may be git checkouts, never copy the repository itself.
+		if entry.IsDir() && entry.Name() == ".git" {
+			return fs.SkipDir
+		}
+
+		targetPath := filepath.Join(destination, filepath.FromSlash(filePath))
+		if entry.IsDir() {
+			return os.MkdirAll(targetPath, os.ModePerm)
+		}
+
+		contents, err := fs.ReadFile(files, filePath)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(filePath, templateFileSuffix) {
+			targetPath = strings.TrimSuffix(targetPath, templateFileSuffix)
+
+			tmpl, err := template.New(path.Base(filePath)).Option("missingkey=error").Parse(string(contents))
+			if err != nil {
+				return utils.NewErrorMessage(fmt.Sprintf("couldn't parse template file [%s]", filePath), err)
+			}
+
+			var rendered bytes.Buffer
+			err = tmpl.Execute(&rendered, data)
+			if err != nil {
+				return utils.NewErrorMessage(fmt.Sprintf("couldn't render template file [%s]", filePath), err)
+			}
+
+			contents = rendered.Bytes()
+		}
+
+		return utils.WriteFile(targetPath, string(contents))
+	})
+}
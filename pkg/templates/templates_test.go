@@ -0,0 +1,68 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zchase/jacuik/pkg/jacuik_config"
+)
+
+func TestServiceTemplateKinds(t *testing.T) {
+	userTemplateDir := t.TempDir()
+	for name, manifest := range map[string]string{
+		"rust-http":   "port: 8000\n",
+		"rust-worker": "kind: worker\nport: 9000\n",
+	} {
+		err := os.MkdirAll(filepath.Join(userTemplateDir, name), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(userTemplateDir, name, templateManifestFile), []byte(manifest), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		kind string
+		port int
+	}{
+		{name: "go-http", port: 8080},
+		{name: "static", port: jacuik_config.DefaultServicePort},
+		{name: "go-worker", kind: jacuik_config.ServiceKindWorker},
+		{name: "rust-http", port: 8000},
+		// Workers don't listen on a port, even if the manifest has one.
+		{name: "rust-worker", kind: jacuik_config.ServiceKindWorker},
+	}
+
+	for _, test := range tests {
+		serviceTemplate, err := GetServiceTemplate(test.name, userTemplateDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if serviceTemplate.Kind != test.kind || serviceTemplate.Port != test.port {
+			t.Errorf("template %s has kind %q and port %d, want %q and %d", test.name, serviceTemplate.Kind, serviceTemplate.Port, test.kind, test.port)
+		}
+	}
+}
+
+func TestServiceTemplateUnknownKind(t *testing.T) {
+	userTemplateDir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(userTemplateDir, "cron"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(userTemplateDir, "cron", templateManifestFile), []byte("kind: job\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ListServiceTemplates(userTemplateDir)
+	want := "The kind of template [cron] must be web or worker, got [job]."
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
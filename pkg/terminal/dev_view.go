@@ -0,0 +1,232 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/zchase/jacuik/pkg/local"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+// maxDevLogLines is how many log lines the dev view keeps.
+const maxDevLogLines = 1000
+
+// stateSymbols are the symbols and colors the dev view shows for the state
+// of a service.
+var stateSymbols = map[string][2]string{
+	local.StateBuilding: {"●", "#f7bf2a"},
+	local.StateRunning:  {"●", "#25a78b"},
+	local.StateFailed:   {"●", "#e53e3e"},
+}
+
+type devLogLine string
+
+type devFinished struct {
+	err error
+}
+
+// DevView shows the logs of the services running locally with a pane
+// below them holding the state of each service.
+type DevView struct {
+	services []string
+	lines    chan string
+	statuses chan local.ServiceStatus
+	done     chan struct{}
+	err      error
+}
+
+// NewDevView creates a view for services.
+func NewDevView(services []string) *DevView {
+	return &DevView{
+		services: services,
+		lines:    make(chan string, 256),
+		statuses: make(chan local.ServiceStatus, 64),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start shows the view and runs handler until the view is closed with
+// ctrl+c, ctx is done or handler returns, handler's error is returned. The
+// ctx handler gets is cancelled when the view is closed and the view waits
+// for handler to return.
+func (v *DevView) Start(ctx context.Context, handler func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	finished := make(chan struct{})
+
+	model := devViewModel{
+		view:     v,
+		statuses: make(map[string]local.ServiceStatus),
+		height:   24,
+		cancel:   cancel,
+		run: func() tea.Msg {
+			defer close(finished)
+			return devFinished{err: handler(ctx)}
+		},
+		finished: finished,
+	}
+
+	err := tea.NewProgram(model).Start()
+	cancel()
+	close(v.done)
+	if err != nil {
+		return err
+	}
+
+	return v.err
+}
+
+// Logs returns a writer whose lines are shown as logs.
+func (v *DevView) Logs() io.Writer {
+	return devLogWriter{view: v}
+}
+
+// SetStatus shows the state of a service.
+func (v *DevView) SetStatus(status local.ServiceStatus) {
+	select {
+	case v.statuses <- status:
+	case <-v.done:
+	}
+}
+
+type devLogWriter struct {
+	view *DevView
+}
+
+func (w devLogWriter) Write(data []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		select {
+		case w.view.lines <- line:
+		case <-w.view.done:
+		}
+	}
+
+	return len(data), nil
+}
+
+type devViewModel struct {
+	view     *DevView
+	statuses map[string]local.ServiceStatus
+	logs     []string
+	width    int
+	height   int
+
+	cancel   context.CancelFunc
+	run      tea.Cmd
+	finished chan struct{}
+}
+
+func watchForLogLines(lines chan string) tea.Cmd {
+	return func() tea.Msg {
+		return devLogLine(<-lines)
+	}
+}
+
+func watchForStatuses(statuses chan local.ServiceStatus) tea.Cmd {
+	return func() tea.Msg {
+		return <-statuses
+	}
+}
+
+func (d devViewModel) Init() tea.Cmd {
+	return tea.Batch(d.run, watchForLogLines(d.view.lines), watchForStatuses(d.view.statuses))
+}
+
+func (d devViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if tea.KeyCtrlC.String() == msg.String() {
+			d.cancel()
+			// The handler is waited for so nothing it started is still
+			// running once the view is closed.
+			return d, func() tea.Msg {
+				<-d.finished
+				return tea.Quit()
+			}
+		}
+	case tea.WindowSizeMsg:
+		d.width = msg.Width
+		d.height = msg.Height
+	case devLogLine:
+		d.logs = append(d.logs, string(msg))
+		if len(d.logs) > maxDevLogLines {
+			d.logs = d.logs[len(d.logs)-maxDevLogLines:]
+		}
+
+		return d, watchForLogLines(d.view.lines)
+	case local.ServiceStatus:
+		d.statuses[msg.Service] = msg
+		return d, watchForStatuses(d.view.statuses)
+	case devFinished:
+		d.view.err = msg.err
+		return d, tea.Quit
+	}
+
+	return d, nil
+}
+
+func (d devViewModel) renderStatuses() string {
+	s := strings.Builder{}
+	s.WriteString("\n    Services\n\n")
+
+	width := 0
+	for _, name := range d.view.services {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	for _, name := range d.view.services {
+		status, ok := d.statuses[name]
+		if !ok {
+			s.WriteString(fmt.Sprintf("        %s %-*s  waiting\n", utils.TextColor("●", "#888888"), width, name))
+			continue
+		}
+
+		symbol := stateSymbols[status.State]
+		line := fmt.Sprintf("        %s %-*s  %-8s", utils.TextColor(symbol[0], symbol[1]), width, name, status.State)
+		if status.URL != "" && status.State == local.StateRunning {
+			line += "  " + status.URL
+		}
+		if status.Message != "" {
+			line += "  " + utils.TextColor(fmt.Sprintf("%s (%s)", status.Message, status.Time.Format("15:04:05")), "#888888")
+		}
+		s.WriteString(line + "\n")
+	}
+
+	s.WriteString("\nPress ctrl+c to stop\n")
+	return s.String()
+}
+
+func (d devViewModel) View() string {
+	statuses := d.renderStatuses()
+
+	logHeight := d.height - strings.Count(statuses, "\n") - 1
+	if logHeight < 0 {
+		logHeight = 0
+	}
+
+	logs := d.logs
+	if len(logs) > logHeight {
+		logs = logs[len(logs)-logHeight:]
+	}
+
+	lineStyle := lipgloss.NewStyle()
+	if d.width > 0 {
+		lineStyle = lineStyle.MaxWidth(d.width)
+	}
+
+	s := strings.Builder{}
+	for i := len(logs); i < logHeight; i++ {
+		s.WriteString("\n")
+	}
+	for _, line := range logs {
+		s.WriteString(lineStyle.Render(line) + "\n")
+	}
+	s.WriteString(statuses)
+
+	return s.String()
+}
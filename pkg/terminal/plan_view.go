@@ -0,0 +1,123 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zchase/jacuik/pkg/infrastructure"
+	"github.com/zchase/jacuik/pkg/utils"
+)
+
+type previewFinished struct {
+	summary *infrastructure.PreviewSummary
+}
+
+// operationSymbols are the symbols and colors the plan shows for each
+// operation.
+var operationSymbols = map[string][2]string{
+	infrastructure.OperationCreate:  {"+ ", "#25a78b"},
+	infrastructure.OperationUpdate:  {"~ ", "#f7bf2a"},
+	infrastructure.OperationReplace: {"+-", "#f7bf2a"},
+	infrastructure.OperationDelete:  {"- ", "#e53e3e"},
+}
+
+// planState is the plan shown once a preview finishes. Each resource can be
+// expanded to show the properties that change.
+type planState struct {
+	summary  *infrastructure.PreviewSummary
+	cursor   int
+	expanded map[int]bool
+}
+
+// NewPreviewView creates a view that shows the progress of a preview and
+// then the plan it produced.
+func NewPreviewView(handler func(writer io.Writer) (*infrastructure.PreviewSummary, error)) *ResourceView {
+	model := resourceViewModel{
+		resources:        make(map[string]resourceOutputUpdate),
+		resourceListener: make(chan infrastructure.ResourceOutput),
+		previewHandler:   handler,
+	}
+
+	return &ResourceView{
+		program: tea.NewProgram(model),
+	}
+}
+
+func (p *planState) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "esc":
+		return tea.Quit
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.summary.Changes)-1 {
+			p.cursor++
+		}
+	case "enter", " ":
+		p.expanded[p.cursor] = !p.expanded[p.cursor]
+	}
+
+	return nil
+}
+
+func (p *planState) render() string {
+	s := strings.Builder{}
+	s.WriteString(fmt.Sprintf("    Plan: %s\n\n", p.summary.Text()))
+
+	for i, change := range p.summary.Changes {
+		cursor := " "
+		if i == p.cursor {
+			cursor = ">"
+		}
+
+		symbol := operationSymbols[change.Operation]
+		s.WriteString(fmt.Sprintf("  %s %s %s %s\n", cursor, utils.TextColor(symbol[0], symbol[1]), change.Name, utils.TextColor(change.Type, "#888888")))
+
+		if p.expanded[i] {
+			s.WriteString(renderResourceChange(change))
+		}
+	}
+
+	if len(p.summary.Changes) == 0 {
+		return s.String()
+	}
+
+	s.WriteString("\n\nPress up/down to move, enter to show a resource's changes and q to exit\n\n")
+	return s.String()
+}
+
+func renderResourceChange(change infrastructure.ResourceChange) string {
+	s := strings.Builder{}
+
+	if change.Operation == infrastructure.OperationReplace && len(change.ReplaceReasons) > 0 {
+		s.WriteString(fmt.Sprintf("          replaced because of: %s\n", strings.Join(change.ReplaceReasons, ", ")))
+	}
+
+	for _, property := range change.Properties {
+		line := ""
+		switch property.Kind {
+		case "add":
+			line = fmt.Sprintf("+ %s: %s", property.Path, infrastructure.FormatPropertyValue(property.New))
+		case "delete":
+			line = fmt.Sprintf("- %s: %s", property.Path, infrastructure.FormatPropertyValue(property.Old))
+		default:
+			line = fmt.Sprintf("~ %s: %s => %s", property.Path, infrastructure.FormatPropertyValue(property.Old), infrastructure.FormatPropertyValue(property.New))
+		}
+
+		if property.Replaces {
+			line += " (forces replacement)"
+		}
+
+		s.WriteString(fmt.Sprintf("          %s\n", line))
+	}
+
+	if len(change.Properties) == 0 && change.Operation != infrastructure.OperationReplace {
+		s.WriteString("          no property changes reported\n")
+	}
+
+	return s.String()
+}
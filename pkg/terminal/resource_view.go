@@ -63,6 +63,11 @@ type resourceViewModel struct {
 	resources             map[string]resourceOutputUpdate
 	resourceActionHandler func(writer io.Writer) error
 	updateQueue           []infrastructure.ResourceOutput
+
+	// previewHandler is set instead of resourceActionHandler for a
+	// preview, the view then shows the plan once the preview finishes.
+	previewHandler func(writer io.Writer) (*infrastructure.PreviewSummary, error)
+	plan           *planState
 }
 
 func watchForEvents(event chan infrastructure.ResourceOutput) tea.Cmd {
@@ -97,6 +102,19 @@ func (r resourceViewModel) Init() tea.Cmd {
 		WriteChannel: r.resourceListener,
 	}
 
+	if r.previewHandler != nil {
+		handler := func() tea.Msg {
+			summary, err := r.previewHandler(infraOutput)
+			if err != nil {
+				return pulumiProgramError(err.Error())
+			}
+
+			return previewFinished{summary: summary}
+		}
+
+		return tea.Batch(handler, watchForEvents(r.resourceListener))
+	}
+
 	handler := func() tea.Msg {
 		err := r.resourceActionHandler(infraOutput)
 		if err != nil {
@@ -119,6 +137,10 @@ func (r resourceViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if tea.KeyCtrlC.String() == msg.String() {
 			return r, tea.Quit
 		}
+
+		if r.plan != nil {
+			return r, r.plan.handleKey(msg)
+		}
 	case infrastructure.ResourceOutput:
 		startProcessing := len(r.updateQueue) == 0
 		r.updateQueue = append(r.updateQueue, msg)
@@ -160,6 +182,21 @@ func (r resourceViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return r, tea.Quit
 
+	case previewFinished:
+		if len(r.updateQueue) > 0 {
+			return r, tea.Tick(time.Millisecond*500, func(t time.Time) tea.Msg {
+				return msg
+			})
+		}
+
+		// Without changes there is nothing to look at in the plan.
+		r.plan = &planState{summary: msg.summary, expanded: make(map[int]bool)}
+		if len(msg.summary.Changes) == 0 {
+			return r, tea.Quit
+		}
+
+		return r, nil
+
 	// TODO: proper error handling
 	case pulumiProgramError:
 		fmt.Println(msg)
@@ -196,6 +233,10 @@ func renderContent(resources map[string]resourceOutputUpdate) string {
 }
 
 func (r resourceViewModel) View() string {
+	if r.plan != nil {
+		return r.plan.render()
+	}
+
 	content := renderContent(r.resources)
 	s := fmt.Sprintf("%s\n", content)
 	return s
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// DockerIgnore matches the files a .dockerignore leaves out of a build
+// context, using the same pattern matching as Docker.
+type DockerIgnore struct {
+	matcher *patternmatcher.PatternMatcher
+}
+
+// LoadDockerIgnore reads the .dockerignore of a build context. A context
+// without one ignores nothing.
+func LoadDockerIgnore(contextDirectory string) (*DockerIgnore, error) {
+	file, err := os.Open(filepath.Join(contextDirectory, ".dockerignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &DockerIgnore{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	patterns, err := ignorefile.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DockerIgnore{matcher: matcher}, nil
+}
+
+// Ignored reports whether the file at relativePath, a slash separated path
+// in the build context, is left out of it. Docker always sends the
+// Dockerfile and the .dockerignore.
+func (d *DockerIgnore) Ignored(relativePath string) bool {
+	if d == nil || d.matcher == nil || relativePath == "Dockerfile" || relativePath == ".dockerignore" {
+		return false
+	}
+
+	ignored, err := d.matcher.MatchesOrParentMatches(filepath.FromSlash(relativePath))
+	return err == nil && ignored
+}
+
+// SkipDirectory reports whether nothing in the directory at relativePath
+// can be in the build context, so it doesn't need to be walked. An ignored
+// directory may still have files brought back by a ! pattern.
+func (d *DockerIgnore) SkipDirectory(relativePath string) bool {
+	return d.Ignored(relativePath) && !d.matcher.Exclusions()
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadTestDockerIgnore(t *testing.T, contents string) *DockerIgnore {
+	t.Helper()
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ignored, err := LoadDockerIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ignored
+}
+
+func TestDockerIgnoreIgnored(t *testing.T) {
+	ignored := loadTestDockerIgnore(t, `# Dependencies and build output.
+node_modules
+dist/
+*.log
+!important.log
+docs/**/*.md
+Dockerfile
+.dockerignore
+`)
+
+	tests := map[string]bool{
+		"main.go":                    false,
+		"node_modules":               true,
+		"node_modules/left-pad/a.js": true,
+		"dist/app.js":                true,
+		"server.log":                 true,
+		"important.log":              false,
+		"docs/guide/intro.md":        true,
+		"docs/logo.png":              false,
+		"src/node_modules.go":        false,
+		// Docker always sends these, whatever the patterns say.
+		"Dockerfile":    false,
+		".dockerignore": false,
+	}
+
+	for relativePath, want := range tests {
+		if got := ignored.Ignored(relativePath); got != want {
+			t.Errorf("Ignored(%q) = %v, want %v", relativePath, got, want)
+		}
+	}
+}
+
+func TestDockerIgnoreSkipDirectory(t *testing.T) {
+	ignored := loadTestDockerIgnore(t, "vendor\nbuild\n")
+	if !ignored.SkipDirectory("vendor") || !ignored.SkipDirectory("build") {
+		t.Errorf("ignored directories are walked")
+	}
+	if ignored.SkipDirectory("src") {
+		t.Errorf("a directory that isn't ignored is skipped")
+	}
+
+	// A ! pattern may bring back files from an ignored directory, so it has
+	// to be walked.
+	exclusions := loadTestDockerIgnore(t, "build\n!build/keep.txt\n")
+	if exclusions.SkipDirectory("build") {
+		t.Errorf("a directory with files brought back by a ! pattern is skipped")
+	}
+	if !exclusions.Ignored("build/other.txt") || exclusions.Ignored("build/keep.txt") {
+		t.Errorf("the ! pattern isn't applied to the files of the directory")
+	}
+}
+
+func TestDockerIgnoreMissing(t *testing.T) {
+	ignored, err := LoadDockerIgnore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ignored.Ignored("node_modules") || ignored.SkipDirectory("node_modules") {
+		t.Errorf("a build context without a .dockerignore ignores files")
+	}
+
+	var none *DockerIgnore
+	if none.Ignored("node_modules") {
+		t.Errorf("a nil DockerIgnore ignores files")
+	}
+}
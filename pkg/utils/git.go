@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"os/exec"
+)
+
+// InitGitRepository initializes a new git repository in a directory.
+func InitGitRepository(dirPath string) error {
+	cmd := exec.Command("git", "init", "--quiet")
+	cmd.Dir = dirPath
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return NewErrorMessage(string(output), err)
+	}
+
+	return nil
+}